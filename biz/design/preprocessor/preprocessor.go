@@ -5,48 +5,136 @@ import (
 
 	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
 	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
 	"github.com/leeseika/cv-demo/pkg/page/material/template"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
 	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
 func PreprocessComponent(
 	raw json.RawMessage,
-	locale string,
-	localeProvider locale.LocaleProvider,
 ) (*component.Schema, error) {
 	var rawComponentSchema jsonmodel.ComponentSchema
 	if err := json.Unmarshal(raw, &rawComponentSchema); err != nil {
 		return nil, err
 	}
-	componentSchema, err := component.Parse(rawComponentSchema, locale, localeProvider)
+	componentSchema, err := component.Parse(rawComponentSchema)
 	if err != nil {
 		return nil, err
 	}
 	return componentSchema, nil
 }
 
+func PreprocessThemeSettingsSchema(
+	raw json.RawMessage,
+) (*theme.ThemeSettingsSchema, error) {
+	var rawThemeSettingsSchema jsonmodel.ThemeSettingsSchema
+	if err := json.Unmarshal(raw, &rawThemeSettingsSchema); err != nil {
+		return nil, err
+	}
+	return theme.Parse(rawThemeSettingsSchema)
+}
+
+func PreprocessPageTypeSchema(
+	raw json.RawMessage,
+) (*pagetype.Schema, error) {
+	var rawPageTypeSchema jsonmodel.PageTypeSchema
+	if err := json.Unmarshal(raw, &rawPageTypeSchema); err != nil {
+		return nil, err
+	}
+	return pagetype.Parse(rawPageTypeSchema)
+}
+
+// PreprocessJSONTemplate parses and validates a page template, invalid values
+// are replaced with the element defaults resolved for localeCode
 func PreprocessJSONTemplate(
 	raw json.RawMessage,
 	schemaProvider componentschema.ComponentSchemaProvider,
+	localeCode string,
+	localeProvider locale.LocaleProvider,
+	additionalHandlers ...template.ElementValueHandler,
+) (*template.JSONTemplate, error) {
+	return PreprocessPage(raw, schemaProvider, PageBindings{}, localeCode, localeProvider, additionalHandlers...)
+}
+
+// PageBindings are the preprocessed materials a page template refers to
+type PageBindings struct {
+	// Layout is the preprocessed layout named by the layout of the page
+	Layout *template.Layout
+	// PageType is the preprocessed schema named by the page type of the page
+	PageType *pagetype.Schema
+	// Theme is the global settings of the theme the page is rendered with
+	Theme *ThemeBinding
+}
+
+// ThemeBinding is the preprocessed theme settings schema and the global
+// setting values, they are validated along with the page
+type ThemeBinding struct {
+	Schema   *theme.ThemeSettingsSchema
+	Settings theme.Settings
+}
+
+// PreprocessPage is PreprocessJSONTemplate for pages that refer to other
+// materials, they are bound before validation
+func PreprocessPage(
+	raw json.RawMessage,
+	schemaProvider componentschema.ComponentSchemaProvider,
+	bindings PageBindings,
+	localeCode string,
+	localeProvider locale.LocaleProvider,
 	additionalHandlers ...template.ElementValueHandler,
 ) (*template.JSONTemplate, error) {
 	tpl, err := template.ParseJSON(raw, schemaProvider)
 	if err != nil {
 		return nil, err
 	}
-
-	handlers := []template.ElementValueHandler{
-		// built-in handlers
-		template.NewElementValueChecker(),
-		template.NewElementValueDefaultSetter(),
+	if bindings.Layout != nil {
+		tpl.SetLayout(bindings.Layout)
+	}
+	if bindings.PageType != nil {
+		tpl.SetPageType(bindings.PageType)
+	}
+	if bindings.Theme != nil {
+		tpl.SetThemeSettings(bindings.Theme.Schema, bindings.Theme.Settings)
 	}
-	// append additional handlers
-	handlers = append(handlers, additionalHandlers...)
 
-	err = tpl.Validate(handlers...)
+	err = tpl.Validate(withBuiltinHandlers(localeCode, localeProvider, additionalHandlers)...)
 	if err != nil {
 		return nil, err
 	}
 	return tpl, nil
 }
+
+// PreprocessLayout parses the layout and validates its section groups once,
+// groups are parsed json templates keyed by name
+func PreprocessLayout(
+	raw json.RawMessage,
+	groups map[string]*template.JSONTemplate,
+	localeCode string,
+	localeProvider locale.LocaleProvider,
+	additionalHandlers ...template.ElementValueHandler,
+) (*template.Layout, error) {
+	layout, err := template.ParseLayout(raw, groups)
+	if err != nil {
+		return nil, err
+	}
+	if err := layout.Validate(withBuiltinHandlers(localeCode, localeProvider, additionalHandlers)...); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+func withBuiltinHandlers(
+	localeCode string,
+	localeProvider locale.LocaleProvider,
+	additionalHandlers []template.ElementValueHandler,
+) []template.ElementValueHandler {
+	handlers := []template.ElementValueHandler{
+		// built-in handlers
+		template.NewElementValueChecker(),
+		template.NewElementValueDefaultSetter(localeCode, localeProvider),
+	}
+	// append additional handlers
+	return append(handlers, additionalHandlers...)
+}
@@ -0,0 +1,107 @@
+package preprocessor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/template"
+	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+)
+
+// buildLargeTemplate builds a page template with numComponents product
+// description components holding numBlocks blocks each
+func buildLargeTemplate(numComponents, numBlocks int) []byte {
+	components := make(map[string]any, numComponents)
+	order := make([]string, 0, numComponents)
+	for i := range numComponents {
+		compID := fmt.Sprintf("comp_product_description_%d", i)
+		blockOrder := make([]string, 0, numBlocks)
+		blockSettings := make(map[string]any, numBlocks)
+		for j := range numBlocks {
+			blockID := fmt.Sprintf("blc_description_%d", j)
+			blockOrder = append(blockOrder, blockID)
+			blockSettings[blockID] = map[string]any{
+				"id":   blockID,
+				"type": "description",
+				"element_settings": map[string]any{
+					"description_content":     fmt.Sprintf("Description %d of component %d", j, i),
+					"description_size":        "h1",
+					"description_line_height": 28,
+				},
+			}
+		}
+		components[compID] = map[string]any{
+			"id":   compID,
+			"name": "product_description",
+			"element_settings": map[string]any{
+				"padding_top":    50,
+				"padding_bottom": 50,
+			},
+			"block_order": blockOrder,
+			"blocks":      blockSettings,
+		}
+		order = append(order, compID)
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"name":       "large_page",
+		"components": components,
+		"order":      order,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func newBenchSchemaProvider(b *testing.B) componentschema.ComponentSchemaProvider {
+	productDescriptionComponentSchema, err := PreprocessComponent(productDescriptionSchemaRaw)
+	if err != nil {
+		b.Fatalf("failed to handle product description component schema: %v", err)
+	}
+	return componentschema.NewInMemorySchemaProvider(map[string]component.Schema{
+		"product_description": *productDescriptionComponentSchema,
+	})
+}
+
+func BenchmarkParseAndValidate(b *testing.B) {
+	raw := buildLargeTemplate(50, 200)
+	schemaProvider := newBenchSchemaProvider(b)
+	handlers := []template.ElementValueHandler{
+		template.NewElementValueChecker(),
+		template.NewElementValueDefaultSetter("en-US", enUSProvider),
+	}
+
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		tpl, err := template.ParseJSON(raw, schemaProvider)
+		if err != nil {
+			b.Fatalf("failed to parse template: %v", err)
+		}
+		if err := tpl.Validate(handlers...); err != nil {
+			b.Fatalf("failed to validate template: %v", err)
+		}
+	}
+}
+
+func BenchmarkStreamValidate(b *testing.B) {
+	raw := buildLargeTemplate(50, 200)
+	schemaProvider := newBenchSchemaProvider(b)
+	handlers := []template.ElementValueHandler{
+		template.NewElementValueChecker(),
+		template.NewElementValueDefaultSetter("en-US", enUSProvider),
+	}
+
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		_, err := template.StreamValidate(bytes.NewReader(raw), schemaProvider, nil, handlers...)
+		if err != nil {
+			b.Fatalf("failed to stream template: %v", err)
+		}
+	}
+}
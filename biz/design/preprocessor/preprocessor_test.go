@@ -2,13 +2,20 @@ package preprocessor
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/leeseika/cv-demo/pkg/jsonx"
 	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+	"github.com/leeseika/cv-demo/pkg/page/material/template"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
 	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 	"github.com/microcosm-cc/bluemonday"
+	"github.com/osteele/liquid/values"
 )
 
 var (
@@ -17,6 +24,12 @@ var (
 	localeZhCNRaw               []byte
 	productTitleSchemaRaw       []byte
 	productDescriptionSchemaRaw []byte
+	themeSettingsSchemaRaw      []byte
+	headerGroupRaw              []byte
+	footerGroupRaw              []byte
+	defaultLayoutRaw            []byte
+
+	enUSProvider locale.LocaleProvider
 )
 
 func init() {
@@ -29,6 +42,7 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	enUSProvider = locale.NewJSONProvider(localeEnUSRaw)
 	localeZhCNRaw, err = os.ReadFile("./test-data/locale/zh-CN.json")
 	if err != nil {
 		panic(err)
@@ -41,82 +55,306 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	themeSettingsSchemaRaw, err = os.ReadFile("./test-data/theme/settings_schema.json")
+	if err != nil {
+		panic(err)
+	}
+	headerGroupRaw, err = os.ReadFile("./test-data/template/header.json")
+	if err != nil {
+		panic(err)
+	}
+	footerGroupRaw, err = os.ReadFile("./test-data/template/footer.json")
+	if err != nil {
+		panic(err)
+	}
+	defaultLayoutRaw, err = os.ReadFile("./test-data/layout/default.json")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// productSchemas preprocesses the product title and description schemas,
+// raw schemas in overrides are added or replace them by component name
+func productSchemas(t *testing.T, overrides map[string][]byte) (map[string]component.Schema, componentschema.ComponentSchemaProvider) {
+	t.Helper()
+	raws := map[string][]byte{
+		"product_title":       productTitleSchemaRaw,
+		"product_description": productDescriptionSchemaRaw,
+	}
+	for name, raw := range overrides {
+		raws[name] = raw
+	}
+	schemas := make(map[string]component.Schema, len(raws))
+	for name, raw := range raws {
+		schema, err := PreprocessComponent(raw)
+		if err != nil {
+			t.Fatalf("failed to handle %s component schema: %v", name, err)
+		}
+		schemas[name] = *schema
+	}
+	return schemas, componentschema.NewInMemorySchemaProvider(schemas)
+}
+
+// mutation sets value at path of a raw json fixture
+type mutation struct {
+	path  string
+	value any
+}
+
+// mutate returns a copy of raw with the mutations applied in order
+func mutate(t *testing.T, raw []byte, mutations []mutation) []byte {
+	t.Helper()
+	val := jsonx.JSONValue{RawMessage: append([]byte(nil), raw...)}
+	for _, m := range mutations {
+		if err := val.Set(m.path, m.value); err != nil {
+			t.Fatalf("failed to set %s: %v", m.path, err)
+		}
+	}
+	return val.RawMessage
+}
+
+// parsePage parses raw without validating it
+func parsePage(t *testing.T, raw []byte, schemaProvider componentschema.ComponentSchemaProvider) *template.JSONTemplate {
+	t.Helper()
+	tpl, err := template.ParseJSON(raw, schemaProvider)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	return tpl
 }
 
 func TestPreprocessProductPage(t *testing.T) {
 	enProvider := locale.NewJSONProvider(localeEnUSRaw)
 	zhProvider := locale.NewJSONProvider(localeZhCNRaw)
 
+	// preprocess component schemas once, they are locale independent
+	productTitleComponentSchema, err := PreprocessComponent(productTitleSchemaRaw)
+	if err != nil {
+		t.Fatalf("failed to handle product title component schema: %v", err)
+	}
+	productDescriptionComponentSchema, err := PreprocessComponent(productDescriptionSchemaRaw)
+	if err != nil {
+		t.Fatalf("failed to handle product description component schema: %v", err)
+	}
+	// prepare component schema provider
+	schemaMap := map[string]component.Schema{
+		"product_title":       *productTitleComponentSchema,
+		"product_description": *productDescriptionComponentSchema,
+	}
+	componentSchemaProvider := componentschema.NewInMemorySchemaProvider(schemaMap)
+
 	tests := []struct {
 		name           string
 		locale         string
 		localeProvider locale.LocaleProvider
+		expectedName   string
 	}{
 		{
 			name:           "en-US",
 			locale:         "en-US",
 			localeProvider: enProvider,
+			expectedName:   "product sub title",
 		},
 		{
 			name:           "zh-CN",
 			locale:         "zh-CN",
 			localeProvider: zhProvider,
+			expectedName:   "副标题",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// preprocess component schemas
-			// parse raw component schemas
-			productTitleComponentSchema, err := PreprocessComponent(
-				productTitleSchemaRaw,
-				tt.locale,
-				tt.localeProvider,
-			)
-			if err != nil {
-				t.Fatalf("failed to handle product title component schema: %v", err)
-			}
-			productDescriptionComponentSchema, err := PreprocessComponent(
-				productDescriptionSchemaRaw,
-				tt.locale,
-				tt.localeProvider,
-			)
-			if err != nil {
-				t.Fatalf("failed to handle product description component schema: %v", err)
+			// localize the shared component schemas for the request locale
+			localizedProductTitleSchema := productTitleComponentSchema.Localize(tt.locale, tt.localeProvider)
+			localizedProductDescriptionSchema := productDescriptionComponentSchema.Localize(tt.locale, tt.localeProvider)
+
+			if got := localizedProductTitleSchema.Blocks[1].Name.String(); got != tt.expectedName {
+				t.Fatalf("expected localized block name %q, got %q", tt.expectedName, got)
 			}
-			// prepare component schema provider
-			schemaMap := map[string]component.Schema{
-				"product_title":       *productTitleComponentSchema,
-				"product_description": *productDescriptionComponentSchema,
+			// the shared schema keeps its translation keys
+			if got := productTitleComponentSchema.Blocks[1].Name.String(); got != "t:components.product_title.blocks.sub_title.name" {
+				t.Fatalf("expected shared schema to keep translation key, got %q", got)
 			}
-			componentSchemaProvider := componentschema.NewInMemorySchemaProvider(schemaMap)
 
-			parsedProductTitleSchema, _ := json.Marshal(productTitleComponentSchema)
-			parsedProductDescriptionSchema, _ := json.Marshal(productDescriptionComponentSchema)
+			parsedProductTitleSchema, _ := json.Marshal(localizedProductTitleSchema)
+			parsedProductDescriptionSchema, _ := json.Marshal(localizedProductDescriptionSchema)
 
 			t.Logf("preprocessed component schema %s with locale %v: %v",
-				productTitleComponentSchema.Name,
+				localizedProductTitleSchema.Name,
 				tt.locale,
 				string(parsedProductTitleSchema),
 			)
 			t.Logf("preprocessed component schema %s with locale %v: %v",
-				productDescriptionComponentSchema.Name,
+				localizedProductDescriptionSchema.Name,
 				tt.locale,
 				string(parsedProductDescriptionSchema),
 			)
 
 			// preprocess product page template
 			// parse and validate JSON template
-			_, err = PreprocessJSONTemplate(
+			jsonTpl, err := PreprocessJSONTemplate(
 				productPageTemplateRaw,
 				componentSchemaProvider,
+				tt.locale,
+				tt.localeProvider,
 				NewElementValueSanitizer(bluemonday.UGCPolicy()),
 			)
 			if err != nil {
 				t.Fatalf("failed to handle product page template: %v", err)
 			}
 
+			props, err := jsonTpl.ToProps(tt.locale)
+			if err != nil {
+				t.Fatalf("failed to convert product page template to props: %v", err)
+			}
+			compProps := props["comp_product_title"].(map[string]any)
+			if code := compProps["locale"].(map[string]any)["code"]; code != tt.locale {
+				t.Fatalf("expected locale code %s, got %v", tt.locale, code)
+			}
+			if paddingTop := compProps["formatted_settings"].(map[string]any)["padding_top"]; paddingTop != "36px" {
+				t.Fatalf("expected formatted padding top 36px, got %v", paddingTop)
+			}
+
 			// t.Logf("preprocessed product page template with locale %v: %+v", tt.locale, jsonTpl)
 		})
 	}
 }
+
+func TestTranslatableDefaults(t *testing.T) {
+	schemaRaw := mutate(t, productTitleSchemaRaw, []mutation{
+		{path: "blocks.0.elements.0.default", value: map[string]string{"en-US": "Product Title", "zh-CN": "商品标题", "default": "Title"}},
+		{path: "blocks.1.elements.0.default", value: "t:components.product_title.blocks.sub_title.name"},
+	})
+	_, componentSchemaProvider := productSchemas(t, map[string][]byte{"product_title": schemaRaw})
+	// invalid values are replaced with the defaults
+	page := mutate(t, productPageTemplateRaw, []mutation{
+		{path: "components.comp_product_title.blocks.blc_title.element_settings.title_text", value: 1},
+		{path: "components.comp_product_title.blocks.blc_sub_title.element_settings.sub_title_text", value: false},
+	})
+
+	tests := []struct {
+		locale           string
+		localeProvider   locale.LocaleProvider
+		expectedTitle    string
+		expectedSubTitle string
+	}{
+		{locale: "en-US", localeProvider: enUSProvider, expectedTitle: "Product Title", expectedSubTitle: "product sub title"},
+		{locale: "zh-CN", localeProvider: locale.NewJSONProvider(localeZhCNRaw), expectedTitle: "商品标题", expectedSubTitle: "副标题"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			jsonTpl, err := PreprocessJSONTemplate(page, componentSchemaProvider, tt.locale, tt.localeProvider)
+			if err != nil {
+				t.Fatalf("failed to handle product page template: %v", err)
+			}
+			blockSettings := jsonTpl.Components["comp_product_title"].Blocks
+			if got := blockSettings["blc_title"].ElementSettings["title_text"].String(); got != tt.expectedTitle {
+				t.Fatalf("expected title default %q, got %q", tt.expectedTitle, got)
+			}
+			if got := blockSettings["blc_sub_title"].ElementSettings["sub_title_text"].String(); got != tt.expectedSubTitle {
+				t.Fatalf("expected sub title default %q, got %q", tt.expectedSubTitle, got)
+			}
+			if _, err := jsonTpl.ToProps(tt.locale); err != nil {
+				t.Fatalf("failed to convert product page template to props: %v", err)
+			}
+		})
+	}
+}
+
+func TestThemeSettingsBinding(t *testing.T) {
+	themeSchema, err := PreprocessThemeSettingsSchema(themeSettingsSchemaRaw)
+	if err != nil {
+		t.Fatalf("failed to handle theme settings schema: %v", err)
+	}
+	_, componentSchemaProvider := productSchemas(t, nil)
+
+	headingFont, _ := jsonx.NewString("serif")
+	bindings := PageBindings{Theme: &ThemeBinding{Schema: themeSchema, Settings: theme.Settings{
+		"heading_font":   *headingFont,
+		"base_font_size": *jsonx.NewNumber(int64(100)),
+	}}}
+	tpl, err := PreprocessPage(productPageTemplateRaw, componentSchemaProvider, bindings, "en-US", enUSProvider)
+	if err != nil {
+		t.Fatalf("failed to handle product page template: %v", err)
+	}
+	// the bound settings are validated along with the page
+	if fontSize := tpl.ThemeSettings()["base_font_size"]; fontSize.Int() != 16 {
+		t.Fatalf("expected out of range font size to fall back to 16, got %s", fontSize.RawMessage)
+	}
+
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert product page template to props: %v", err)
+	}
+	if font := props["settings"].(map[string]any)["heading_font"].(values.Value).Interface(); font != "serif" {
+		t.Fatalf("expected heading font serif, got %v", font)
+	}
+}
+
+func TestPreprocessLayout(t *testing.T) {
+	_, componentSchemaProvider := productSchemas(t, nil)
+
+	// an invalid value of a group falls back to its default like in a page
+	header := mutate(t, headerGroupRaw, []mutation{
+		{path: "components.comp_header.blocks.blc_shop_name.element_settings.title_size", value: 1},
+	})
+	groups := make(map[string]*template.JSONTemplate)
+	for _, raw := range [][]byte{header, footerGroupRaw} {
+		group := parsePage(t, raw, componentSchemaProvider)
+		groups[group.Name] = group
+	}
+	layout, err := PreprocessLayout(defaultLayoutRaw, groups, "en-US", enUSProvider)
+	if err != nil {
+		t.Fatalf("failed to handle layout: %v", err)
+	}
+
+	page := mutate(t, productPageTemplateRaw, []mutation{
+		{path: "layout", value: "default"},
+		{path: "slots", value: map[string][]string{"aside": {"comp_product_description"}}},
+	})
+	tpl, err := PreprocessPage(page, componentSchemaProvider, PageBindings{Layout: layout}, "en-US", enUSProvider)
+	if err != nil {
+		t.Fatalf("failed to handle product page template: %v", err)
+	}
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+	expectedOrder := []string{"comp_header", "comp_product_title", "comp_product_description", "comp_footer"}
+	if order := props["order"].([]string); strings.Join(order, ",") != strings.Join(expectedOrder, ",") {
+		t.Fatalf("expected render order %v, got %v", expectedOrder, order)
+	}
+
+	group, _ := layout.Group("header")
+	titleSize := group.Components["comp_header"].Blocks["blc_shop_name"].ElementSettings["title_size"]
+	if titleSize.String() != "h1" {
+		t.Fatalf("expected invalid group value to fall back to h1, got %s", titleSize.RawMessage)
+	}
+}
+
+func TestPageTypeBinding(t *testing.T) {
+	_, componentSchemaProvider := productSchemas(t, nil)
+	pageTypeRaw, err := os.ReadFile("./test-data/page-type/product.json")
+	if err != nil {
+		t.Fatalf("failed to read page type schema: %v", err)
+	}
+	pageType, err := PreprocessPageTypeSchema(pageTypeRaw)
+	if err != nil {
+		t.Fatalf("failed to handle page type schema: %v", err)
+	}
+
+	page := mutate(t, productPageTemplateRaw, []mutation{{path: "page_type", value: "product"}})
+	if _, err := PreprocessPage(page, componentSchemaProvider, PageBindings{PageType: pageType}, "en-US", enUSProvider); err != nil {
+		t.Fatalf("failed to handle product page template: %v", err)
+	}
+	if _, err := PreprocessJSONTemplate(page, componentSchemaProvider, "en-US", enUSProvider); err == nil {
+		t.Fatal("expected error for a page type that is not bound")
+	}
+
+	moved := mutate(t, page, []mutation{{path: "order", value: []string{"comp_product_description", "comp_product_title"}}})
+	_, err = PreprocessPage(moved, componentSchemaProvider, PageBindings{PageType: pageType}, "en-US", enUSProvider)
+	if !errors.Is(err, pagetype.ErrViolation) {
+		t.Fatalf("expected page type violation, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package templatestore
+
+import (
+	"fmt"
+
+	"github.com/leeseika/cv-demo/biz/design/preprocessor"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+	"github.com/leeseika/cv-demo/pkg/page/material/template"
+)
+
+// BindingResolver resolves the preprocessed layouts and page type schemas
+// page templates refer to by name
+type BindingResolver interface {
+	Layout(name string) (*template.Layout, error)
+	PageType(name string) (*pagetype.Schema, error)
+}
+
+type inMemoryBindingResolver struct {
+	layouts   map[string]*template.Layout
+	pageTypes map[string]*pagetype.Schema
+}
+
+func NewInMemoryBindingResolver(layouts map[string]*template.Layout, pageTypes map[string]*pagetype.Schema) BindingResolver {
+	return &inMemoryBindingResolver{
+		layouts:   layouts,
+		pageTypes: pageTypes,
+	}
+}
+
+func (r *inMemoryBindingResolver) Layout(name string) (*template.Layout, error) {
+	layout, ok := r.layouts[name]
+	if !ok {
+		return nil, fmt.Errorf("layout %s not found", name)
+	}
+	return layout, nil
+}
+
+func (r *inMemoryBindingResolver) PageType(name string) (*pagetype.Schema, error) {
+	schema, ok := r.pageTypes[name]
+	if !ok {
+		return nil, fmt.Errorf("page type %s not found", name)
+	}
+	return schema, nil
+}
+
+// resolveBindings resolves the layout and the page type tpl refers to, a nil
+// resolver leaves them unbound so Validate reports them
+func resolveBindings(resolver BindingResolver, tpl *template.JSONTemplate) (preprocessor.PageBindings, error) {
+	var bindings preprocessor.PageBindings
+	if resolver == nil {
+		return bindings, nil
+	}
+	if tpl.Layout != "" {
+		layout, err := resolver.Layout(tpl.Layout)
+		if err != nil {
+			return bindings, err
+		}
+		bindings.Layout = layout
+	}
+	if tpl.PageType != "" {
+		schema, err := resolver.PageType(tpl.PageType)
+		if err != nil {
+			return bindings, err
+		}
+		bindings.PageType = schema
+	}
+	return bindings, nil
+}
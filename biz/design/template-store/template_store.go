@@ -0,0 +1,313 @@
+package templatestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leeseika/cv-demo/biz/design/preprocessor"
+	"github.com/leeseika/cv-demo/pkg/datatype"
+	"github.com/leeseika/cv-demo/pkg/page/material/template"
+	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrRevisionNotFound = errors.New("template revision not found")
+	ErrVersionConflict  = errors.New("template was modified concurrently")
+)
+
+// PageTemplate holds the draft of a page template. Version is bumped by every
+// change and has to be passed back on the next change, a stale version fails
+// with ErrVersionConflict instead of overwriting someone else's work.
+type PageTemplate struct {
+	ID                uint   `gorm:"primaryKey"`
+	Name              string `gorm:"uniqueIndex;size:255"`
+	Draft             datatype.JSON[template.JSONTemplate]
+	Version           int64
+	PublishedRevision int64
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// TemplateRevision is an immutable published state of a page template,
+// revisions are numbered from 1 per template
+type TemplateRevision struct {
+	ID         uint  `gorm:"primaryKey"`
+	TemplateID uint  `gorm:"uniqueIndex:idx_template_revision"`
+	Revision   int64 `gorm:"uniqueIndex:idx_template_revision"`
+	Content    datatype.JSON[template.JSONTemplate]
+	// RestoredFrom is the revision a rollback restored, 0 for a publish
+	RestoredFrom int64
+	CreatedAt    time.Time
+}
+
+type TemplateStore struct {
+	db             *gorm.DB
+	schemaProvider componentschema.ComponentSchemaProvider
+	resolver       BindingResolver
+	localeCode     string
+	localeProvider locale.LocaleProvider
+	handlers       []template.ElementValueHandler
+}
+
+// NewTemplateStore creates a store validating published templates with
+// PreprocessPage, the layouts and page types templates refer to are bound
+// through resolver. Defaults replacing invalid values are stored in localeCode
+// and handlers are passed on as additional handlers.
+func NewTemplateStore(
+	db *gorm.DB,
+	schemaProvider componentschema.ComponentSchemaProvider,
+	resolver BindingResolver,
+	localeCode string,
+	localeProvider locale.LocaleProvider,
+	handlers ...template.ElementValueHandler,
+) *TemplateStore {
+	return &TemplateStore{
+		db:             db,
+		schemaProvider: schemaProvider,
+		resolver:       resolver,
+		localeCode:     localeCode,
+		localeProvider: localeProvider,
+		handlers:       handlers,
+	}
+}
+
+func (s *TemplateStore) AutoMigrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&PageTemplate{}, &TemplateRevision{})
+}
+
+// SaveDraft saves raw as the draft of the template named name. A version of 0
+// creates the template, drafts only have to parse, they are validated on publish.
+func (s *TemplateStore) SaveDraft(ctx context.Context, name string, raw json.RawMessage, version int64) (*PageTemplate, error) {
+	tpl, err := template.ParseJSON(raw, s.schemaProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	var pageTemplate PageTemplate
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if version != 0 {
+			if err := s.update(tx, name, version, map[string]any{"draft": datatype.NewJSON(*tpl)}); err != nil {
+				return err
+			}
+			return tx.Where("name = ?", name).Take(&pageTemplate).Error
+		}
+
+		var count int64
+		if err := tx.Model(&PageTemplate{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: template %s already exists", ErrVersionConflict, name)
+		}
+		pageTemplate = PageTemplate{
+			Name:    name,
+			Draft:   datatype.NewJSON(*tpl),
+			Version: 1,
+		}
+		// a concurrent create still fails on the unique name
+		return tx.Create(&pageTemplate).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pageTemplate, nil
+}
+
+// Get returns the template named name with its draft
+func (s *TemplateStore) Get(ctx context.Context, name string) (*PageTemplate, error) {
+	var pageTemplate PageTemplate
+	if err := s.take(s.db.WithContext(ctx), name, &pageTemplate); err != nil {
+		return nil, err
+	}
+	return &pageTemplate, nil
+}
+
+// Publish validates the draft and publishes it as a new revision
+func (s *TemplateStore) Publish(ctx context.Context, name string, version int64) (*TemplateRevision, error) {
+	var revision *TemplateRevision
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pageTemplate PageTemplate
+		if err := s.take(tx, name, &pageTemplate); err != nil {
+			return err
+		}
+		if pageTemplate.Version != version {
+			return fmt.Errorf("%w: template %s is at version %d, got %d", ErrVersionConflict, name, pageTemplate.Version, version)
+		}
+
+		var err error
+		revision, err = s.publish(tx, &pageTemplate, pageTemplate.Draft.Data(), 0, version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// Rollback publishes the content of an earlier revision as a new revision,
+// the draft is reset to it as well. The content is validated again as the
+// component schemas may have changed since.
+func (s *TemplateStore) Rollback(ctx context.Context, name string, toRevision int64, version int64) (*TemplateRevision, error) {
+	var revision *TemplateRevision
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pageTemplate PageTemplate
+		if err := s.take(tx, name, &pageTemplate); err != nil {
+			return err
+		}
+		if pageTemplate.Version != version {
+			return fmt.Errorf("%w: template %s is at version %d, got %d", ErrVersionConflict, name, pageTemplate.Version, version)
+		}
+
+		var restored TemplateRevision
+		err := tx.Where("template_id = ? AND revision = ?", pageTemplate.ID, toRevision).Take(&restored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: template %s revision %d", ErrRevisionNotFound, name, toRevision)
+		}
+		if err != nil {
+			return err
+		}
+
+		revision, err = s.publish(tx, &pageTemplate, restored.Content.Data(), toRevision, version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// Published returns the validated template of the latest revision, bound to
+// its layout and page type
+func (s *TemplateStore) Published(ctx context.Context, name string) (*template.JSONTemplate, error) {
+	db := s.db.WithContext(ctx)
+	var pageTemplate PageTemplate
+	if err := s.take(db, name, &pageTemplate); err != nil {
+		return nil, err
+	}
+	if pageTemplate.PublishedRevision == 0 {
+		return nil, fmt.Errorf("%w: template %s is not published", ErrRevisionNotFound, name)
+	}
+
+	var revision TemplateRevision
+	err := db.Where("template_id = ? AND revision = ?", pageTemplate.ID, pageTemplate.PublishedRevision).Take(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.attach(revision.Content.Data())
+}
+
+// Revisions returns the revisions of the template named name, oldest first
+func (s *TemplateStore) Revisions(ctx context.Context, name string) ([]TemplateRevision, error) {
+	db := s.db.WithContext(ctx)
+	var pageTemplate PageTemplate
+	if err := s.take(db, name, &pageTemplate); err != nil {
+		return nil, err
+	}
+
+	var revisions []TemplateRevision
+	err := db.Where("template_id = ?", pageTemplate.ID).Order("revision").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (s *TemplateStore) take(db *gorm.DB, name string, pageTemplate *PageTemplate) error {
+	err := db.Where("name = ?", name).Take(pageTemplate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return err
+}
+
+// update applies updates when the template is still at version and bumps the version
+func (s *TemplateStore) update(tx *gorm.DB, name string, version int64, updates map[string]any) error {
+	updates["version"] = version + 1
+	result := tx.Model(&PageTemplate{}).
+		Where("name = ? AND version = ?", name, version).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// tell a missing template from a stale version
+	var pageTemplate PageTemplate
+	if err := s.take(tx, name, &pageTemplate); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: template %s is at version %d, got %d", ErrVersionConflict, name, pageTemplate.Version, version)
+}
+
+// publish validates content and stores it as the next revision of pageTemplate
+func (s *TemplateStore) publish(
+	tx *gorm.DB,
+	pageTemplate *PageTemplate,
+	content template.JSONTemplate,
+	restoredFrom int64,
+	version int64,
+) (*TemplateRevision, error) {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := resolveBindings(s.resolver, &content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve template %s: %w", pageTemplate.Name, err)
+	}
+	validated, err := preprocessor.PreprocessPage(raw, s.schemaProvider, bindings, s.localeCode, s.localeProvider, s.handlers...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate template %s: %w", pageTemplate.Name, err)
+	}
+
+	revision := TemplateRevision{
+		TemplateID:   pageTemplate.ID,
+		Revision:     pageTemplate.PublishedRevision + 1,
+		Content:      datatype.NewJSON(*validated),
+		RestoredFrom: restoredFrom,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+
+	updates := map[string]any{"published_revision": revision.Revision}
+	if restoredFrom > 0 {
+		updates["draft"] = datatype.NewJSON(content)
+	}
+	if err := s.update(tx, pageTemplate.Name, version, updates); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// attach binds a stored template to the schema provider, the layout and the
+// page type of the store
+func (s *TemplateStore) attach(tpl template.JSONTemplate) (*template.JSONTemplate, error) {
+	raw, err := json.Marshal(tpl)
+	if err != nil {
+		return nil, err
+	}
+	attached, err := template.ParseJSON(raw, s.schemaProvider)
+	if err != nil {
+		return nil, err
+	}
+	bindings, err := resolveBindings(s.resolver, attached)
+	if err != nil {
+		return nil, err
+	}
+	if bindings.Layout != nil {
+		attached.SetLayout(bindings.Layout)
+	}
+	if bindings.PageType != nil {
+		attached.SetPageType(bindings.PageType)
+	}
+	return attached, nil
+}
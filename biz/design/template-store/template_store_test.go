@@ -0,0 +1,292 @@
+package templatestore
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/biz/design/preprocessor"
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+	"github.com/leeseika/cv-demo/pkg/page/material/template"
+	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestStore(t *testing.T) *TemplateStore {
+	t.Helper()
+	schemaMap := make(map[string]component.Schema)
+	for _, name := range []string{"product_title", "product_description"} {
+		raw, err := os.ReadFile("../preprocessor/test-data/component-schema/" + name + ".json")
+		if err != nil {
+			t.Fatalf("failed to read %s schema: %v", name, err)
+		}
+		schema, err := preprocessor.PreprocessComponent(raw)
+		if err != nil {
+			t.Fatalf("failed to handle %s schema: %v", name, err)
+		}
+		schemaMap[name] = *schema
+	}
+
+	schemaProvider := componentschema.NewInMemorySchemaProvider(schemaMap)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	store := NewTemplateStore(db, schemaProvider, newTestResolver(t, schemaProvider), "en-US", nil)
+	if err := store.AutoMigrate(t.Context()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return store
+}
+
+// newTestResolver resolves the default layout and the product page type
+func newTestResolver(t *testing.T, schemaProvider componentschema.ComponentSchemaProvider) BindingResolver {
+	t.Helper()
+	groups := make(map[string]*template.JSONTemplate)
+	for _, name := range []string{"header", "footer"} {
+		raw, err := os.ReadFile("../preprocessor/test-data/template/" + name + ".json")
+		if err != nil {
+			t.Fatalf("failed to read %s group: %v", name, err)
+		}
+		group, err := template.ParseJSON(raw, schemaProvider)
+		if err != nil {
+			t.Fatalf("failed to parse %s group: %v", name, err)
+		}
+		groups[name] = group
+	}
+	layoutRaw, err := os.ReadFile("../preprocessor/test-data/layout/default.json")
+	if err != nil {
+		t.Fatalf("failed to read layout: %v", err)
+	}
+	layout, err := preprocessor.PreprocessLayout(layoutRaw, groups, "en-US", nil)
+	if err != nil {
+		t.Fatalf("failed to handle layout: %v", err)
+	}
+
+	pageTypeRaw, err := os.ReadFile("../preprocessor/test-data/page-type/product.json")
+	if err != nil {
+		t.Fatalf("failed to read page type: %v", err)
+	}
+	pageType, err := preprocessor.PreprocessPageTypeSchema(pageTypeRaw)
+	if err != nil {
+		t.Fatalf("failed to handle page type: %v", err)
+	}
+	return NewInMemoryBindingResolver(
+		map[string]*template.Layout{layout.Name: layout},
+		map[string]*pagetype.Schema{pageType.Name: pageType},
+	)
+}
+
+func readProductPage(t *testing.T) jsonx.JSONValue {
+	t.Helper()
+	raw, err := os.ReadFile("../preprocessor/test-data/template/product_page.json")
+	if err != nil {
+		t.Fatalf("failed to read template: %v", err)
+	}
+	return jsonx.JSONValue{RawMessage: raw}
+}
+
+func TestTemplateStorePublishAndRollback(t *testing.T) {
+	ctx := t.Context()
+	store := newTestStore(t)
+	page := readProductPage(t)
+
+	draft, err := store.SaveDraft(ctx, "product_page", page.RawMessage, 0)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	rev1, err := store.Publish(ctx, "product_page", draft.Version)
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if rev1.Revision != 1 {
+		t.Fatalf("expected revision 1, got %d", rev1.Revision)
+	}
+
+	// change the title size and publish again
+	if err := page.Set("components.comp_product_title.blocks.blc_title.element_settings.title_size", "h2"); err != nil {
+		t.Fatalf("failed to modify template: %v", err)
+	}
+	draft, err = store.SaveDraft(ctx, "product_page", page.RawMessage, 2)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	published, err := store.Published(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to load published template: %v", err)
+	}
+	titleSize := published.Components["comp_product_title"].Blocks["blc_title"].ElementSettings["title_size"]
+	if titleSize.String() != "h2" {
+		t.Fatalf("expected published title size h2, got %s", titleSize.String())
+	}
+
+	current, err := store.Get(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	rev3, err := store.Rollback(ctx, "product_page", 1, current.Version)
+	if err != nil {
+		t.Fatalf("failed to roll back: %v", err)
+	}
+	if rev3.Revision != 3 || rev3.RestoredFrom != 1 {
+		t.Fatalf("expected revision 3 restored from 1, got %d from %d", rev3.Revision, rev3.RestoredFrom)
+	}
+
+	published, err = store.Published(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to load published template: %v", err)
+	}
+	titleSize = published.Components["comp_product_title"].Blocks["blc_title"].ElementSettings["title_size"]
+	if titleSize.String() != "h1" {
+		t.Fatalf("expected rolled back title size h1, got %s", titleSize.String())
+	}
+	if err := published.Validate(); err != nil {
+		t.Fatalf("expected published template to be bound to the schema provider: %v", err)
+	}
+
+	revisions, err := store.Revisions(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to list revisions: %v", err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(revisions))
+	}
+
+	if _, err := store.Rollback(ctx, "product_page", 9, current.Version+1); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrRevisionNotFound, err)
+	}
+}
+
+func TestTemplateStoreConflicts(t *testing.T) {
+	ctx := t.Context()
+	store := newTestStore(t)
+	page := readProductPage(t)
+
+	draft, err := store.SaveDraft(ctx, "product_page", page.RawMessage, 0)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.SaveDraft(ctx, "product_page", page.RawMessage, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected error %v creating a duplicate, got %v", ErrVersionConflict, err)
+	}
+
+	// two editors start from the same version, the second one loses
+	if _, err := store.SaveDraft(ctx, "product_page", page.RawMessage, draft.Version); err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.SaveDraft(ctx, "product_page", page.RawMessage, draft.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected error %v, got %v", ErrVersionConflict, err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected error %v, got %v", ErrVersionConflict, err)
+	}
+
+	if _, err := store.SaveDraft(ctx, "missing", page.RawMessage, 1); !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrTemplateNotFound, err)
+	}
+	if _, err := store.Published(ctx, "product_page"); !errors.Is(err, ErrRevisionNotFound) {
+		t.Fatalf("expected error %v for an unpublished template, got %v", ErrRevisionNotFound, err)
+	}
+}
+
+func TestTemplateStorePublishInvalid(t *testing.T) {
+	ctx := t.Context()
+	store := newTestStore(t)
+	page := readProductPage(t)
+
+	// out of range values fall back to defaults, a component without schema can not
+	if err := page.Set("components.comp_product_title.name", "missing_component"); err != nil {
+		t.Fatalf("failed to modify template: %v", err)
+	}
+	draft, err := store.SaveDraft(ctx, "product_page", page.RawMessage, 0)
+	if err != nil {
+		t.Fatalf("expected an invalid draft to be saved: %v", err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); err == nil {
+		t.Fatal("expected publishing an invalid template to fail")
+	}
+
+	current, err := store.Get(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	if current.PublishedRevision != 0 || current.Version != draft.Version {
+		t.Fatalf("expected failed publish to leave the template unchanged, got revision %d version %d", current.PublishedRevision, current.Version)
+	}
+}
+
+func TestTemplateStorePublishWithBindings(t *testing.T) {
+	ctx := t.Context()
+	store := newTestStore(t)
+	page := readProductPage(t)
+
+	for path, value := range map[string]any{
+		"layout":    "default",
+		"page_type": "product",
+		"slots":     map[string][]string{"aside": {"comp_product_description"}},
+	} {
+		if err := page.Set(path, value); err != nil {
+			t.Fatalf("failed to modify template: %v", err)
+		}
+	}
+	draft, err := store.SaveDraft(ctx, "product_page", page.RawMessage, 0)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	published, err := store.Published(ctx, "product_page")
+	if err != nil {
+		t.Fatalf("failed to load published template: %v", err)
+	}
+	props, err := published.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert published template to props: %v", err)
+	}
+	order := props["order"].([]string)
+	expectedOrder := []string{"comp_header", "comp_product_title", "comp_product_description", "comp_footer"}
+	if strings.Join(order, ",") != strings.Join(expectedOrder, ",") {
+		t.Fatalf("expected render order %v, got %v", expectedOrder, order)
+	}
+	if err := published.Validate(); err != nil {
+		t.Fatalf("expected published template to be bound to its page type: %v", err)
+	}
+
+	// the page type still applies on publish
+	if err := page.Set("order", []string{"comp_product_description", "comp_product_title"}); err != nil {
+		t.Fatalf("failed to modify template: %v", err)
+	}
+	if err := page.Set("slots", map[string][]string{}); err != nil {
+		t.Fatalf("failed to modify template: %v", err)
+	}
+	draft, err = store.SaveDraft(ctx, "product_page", page.RawMessage, draft.Version+1)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); err == nil || !strings.Contains(err.Error(), "static component comp_product_title") {
+		t.Fatalf("expected static component error, got %v", err)
+	}
+
+	if err := page.Set("layout", "missing"); err != nil {
+		t.Fatalf("failed to modify template: %v", err)
+	}
+	draft, err = store.SaveDraft(ctx, "product_page", page.RawMessage, draft.Version)
+	if err != nil {
+		t.Fatalf("failed to save draft: %v", err)
+	}
+	if _, err := store.Publish(ctx, "product_page", draft.Version); err == nil || !strings.Contains(err.Error(), "layout missing not found") {
+		t.Fatalf("expected unknown layout error, got %v", err)
+	}
+}
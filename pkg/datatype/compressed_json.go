@@ -0,0 +1,175 @@
+package datatype
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrJSONTooLarge       = errors.New("datatype: decoded json exceeds size limit")
+	ErrUnknownCompression = errors.New("datatype: unknown json compression")
+)
+
+// JSONSizeLimit limits the size of a compressed json column after
+// decompression, so a corrupted or hostile row can not exhaust memory in Scan.
+// Scan runs on a zero value, so the limit is bound through the type.
+type JSONSizeLimit interface {
+	MaxDecodedSize() int64
+}
+
+// DefaultJSONSizeLimit limits decoded json to 32 MiB
+type DefaultJSONSizeLimit struct{}
+
+func (DefaultJSONSizeLimit) MaxDecodedSize() int64 {
+	return 32 << 20
+}
+
+// compressedJSONMagic prefixes compressed payloads and is followed by a codec
+// byte, a json document can never start with a NUL byte so rows written
+// before compression was enabled are read as plain json
+const compressedJSONMagic = "\x00cjz"
+
+const compressionGzip byte = 'g'
+
+// CompressedJSON is JSON[T] stored gzip compressed in a binary column,
+// rows holding plain json are still readable. Rows decoding to more than the
+// limit L are rejected by Scan.
+type CompressedJSON[T any, L JSONSizeLimit] struct {
+	data T
+}
+
+func NewCompressedJSON[L JSONSizeLimit, T any](data T) CompressedJSON[T, L] {
+	return CompressedJSON[T, L]{
+		data: data,
+	}
+}
+
+// Data return data with generic Type T
+func (j CompressedJSON[T, L]) Data() T {
+	return j.data
+}
+
+// Value return the compressed json, implement driver.Valuer interface
+func (j CompressedJSON[T, L]) Value() (driver.Value, error) {
+	ba, err := json.Marshal(j.data)
+	if err != nil {
+		return nil, err
+	}
+	return compressJSON(ba)
+}
+
+// Scan scan value into CompressedJSON[T, L], implements sql.Scanner interface
+func (j *CompressedJSON[T, L]) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		// SQL NULL is read like a json null
+		var zero T
+		j.data = zero
+		return nil
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal compressed JSON value:", value))
+	}
+
+	var limit L
+	ba, err := decompressJSON(raw, limit.MaxDecodedSize())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(ba, &j.data)
+}
+
+// MarshalJSON output the plain json, compression only applies to the column
+func (j CompressedJSON[T, L]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.data)
+}
+
+// UnmarshalJSON to deserialize []byte
+func (j *CompressedJSON[T, L]) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &j.data)
+}
+
+// GormDataType gorm common data type
+func (CompressedJSON[T, L]) GormDataType() string {
+	return "bytes"
+}
+
+// GormDBDataType gorm db data type
+func (CompressedJSON[T, L]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return binaryDBDataType(db)
+}
+
+// binaryDBDataType is the column type of json stored in binary form
+func binaryDBDataType(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		return "BLOB"
+	case "mysql":
+		return "LONGBLOB"
+	case "postgres":
+		return "BYTEA"
+	}
+	return ""
+}
+
+func compressJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(compressedJSONMagic)
+	buf.WriteByte(compressionGzip)
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressJSON returns raw as is when it holds plain json
+func decompressJSON(raw []byte, limit int64) ([]byte, error) {
+	if !bytes.HasPrefix(raw, []byte(compressedJSONMagic)) {
+		if int64(len(raw)) > limit {
+			return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrJSONTooLarge, len(raw), limit)
+		}
+		return raw, nil
+	}
+
+	payload := raw[len(compressedJSONMagic):]
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("%w: missing codec", ErrUnknownCompression)
+	}
+	switch codec := payload[0]; codec {
+	case compressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(payload[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compressed json: %w", err)
+		}
+		defer zr.Close()
+
+		// read one byte past the limit to tell a full buffer from an oversized one
+		ba, err := io.ReadAll(io.LimitReader(zr, limit+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compressed json: %w", err)
+		}
+		if int64(len(ba)) > limit {
+			return nil, fmt.Errorf("%w: limit %d", ErrJSONTooLarge, limit)
+		}
+		return ba, nil
+	default:
+		return nil, fmt.Errorf("%w: codec %q", ErrUnknownCompression, codec)
+	}
+}
@@ -0,0 +1,131 @@
+package datatype
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type compressedPage struct {
+	Title string   `json:"title"`
+	Body  string   `json:"body"`
+	Tags  []string `json:"tags"`
+}
+
+// smallJSONSizeLimit keeps test rows small
+type smallJSONSizeLimit struct{}
+
+func (smallJSONSizeLimit) MaxDecodedSize() int64 {
+	return 64
+}
+
+type compressedRecord struct {
+	ID      uint
+	Content CompressedJSON[compressedPage, DefaultJSONSizeLimit]
+	Summary CompressedJSON[compressedPage, smallJSONSizeLimit]
+}
+
+func TestCompressedJSON(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&compressedRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	page := compressedPage{Title: "home", Body: strings.Repeat("<p>rich text</p>", 1000), Tags: []string{"a"}}
+	record := compressedRecord{Content: NewCompressedJSON[DefaultJSONSizeLimit](page)}
+	if err := db.Create(&record).Error; err != nil {
+		t.Fatalf("failed to create record: %v", err)
+	}
+
+	var stored []byte
+	if err := db.Raw("SELECT content FROM compressed_records WHERE id = ?", record.ID).Row().Scan(&stored); err != nil {
+		t.Fatalf("failed to read raw column: %v", err)
+	}
+	if !strings.HasPrefix(string(stored), compressedJSONMagic) || len(stored) >= len(page.Body) {
+		t.Fatalf("expected a compressed column, got %d bytes", len(stored))
+	}
+
+	// a row written before compression was enabled
+	if err := db.Exec("INSERT INTO compressed_records (id, content) VALUES (?, ?)", 100, `{"title":"legacy"}`).Error; err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+
+	var records []compressedRecord
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("failed to load records: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].Content.Data().Body != page.Body {
+		t.Fatal("expected compressed body to round trip")
+	}
+	if records[1].Content.Data().Title != "legacy" {
+		t.Fatalf("expected legacy title, got %s", records[1].Content.Data().Title)
+	}
+}
+
+func TestCompressedJSONLimit(t *testing.T) {
+	compressed, err := compressJSON([]byte(`"` + strings.Repeat("a", 4096) + `"`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		raw   []byte
+		limit int64
+		err   error
+	}{
+		{name: "within limit", raw: compressed, limit: 4098},
+		{name: "compressed over limit", raw: compressed, limit: 4097, err: ErrJSONTooLarge},
+		{name: "plain over limit", raw: []byte(`"abc"`), limit: 4, err: ErrJSONTooLarge},
+		{name: "unknown codec", raw: []byte(compressedJSONMagic + "x"), limit: 100, err: ErrUnknownCompression},
+		{name: "missing codec", raw: []byte(compressedJSONMagic), limit: 100, err: ErrUnknownCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decompressJSON(tt.raw, tt.limit)
+			if tt.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCompressedJSONColumnLimit(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&compressedRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	page := compressedPage{Title: "home", Body: strings.Repeat("a", 100)}
+	record := compressedRecord{
+		Content: NewCompressedJSON[DefaultJSONSizeLimit](page),
+		Summary: NewCompressedJSON[smallJSONSizeLimit](page),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		t.Fatalf("failed to create record: %v", err)
+	}
+	// the limit of the summary column does not apply to the content column
+	if err := db.Select("content").First(&compressedRecord{}, record.ID).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.First(&compressedRecord{}, record.ID).Error; !errors.Is(err, ErrJSONTooLarge) {
+		t.Fatalf("expected error %v, got %v", ErrJSONTooLarge, err)
+	}
+}
+
+func TestCompressedJSONScanNull(t *testing.T) {
+	content := NewCompressedJSON[DefaultJSONSizeLimit](compressedPage{Title: "home"})
+	if err := content.Scan(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content.Data().Title != "" {
+		t.Fatalf("expected SQL NULL to scan into the zero value, got %s", content.Data().Title)
+	}
+}
@@ -0,0 +1,250 @@
+package datatype
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrKeyNotFound       = errors.New("datatype: encryption key not found")
+	ErrInvalidCiphertext = errors.New("datatype: invalid ciphertext")
+	ErrRedactedJSON      = errors.New("datatype: can not unmarshal a redacted encrypted json")
+)
+
+// KeyProvider supplies the AES keys of encrypted columns. Keys have to be
+// 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with
+	CurrentKey() (keyID string, key []byte, err error)
+	// Key returns the key values written under keyID are decrypted with
+	Key(keyID string) ([]byte, error)
+}
+
+// KeyRing selects the key provider of an EncryptedJSON column. Scan runs on
+// a zero value, so the provider is bound through the type: declare an empty
+// struct type per set of columns sharing keys and implement KeyProvider on it.
+type KeyRing interface {
+	KeyProvider() (KeyProvider, error)
+}
+
+func keyProviderOf[K KeyRing]() (KeyProvider, error) {
+	var ring K
+	provider, err := ring.KeyProvider()
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("%w: no key provider for %T", ErrKeyNotFound, ring)
+	}
+	return provider, nil
+}
+
+// StaticKeyProvider holds its keys in memory. Rotating keys means adding the
+// new key and making it current, older keys stay to read existing rows.
+type StaticKeyProvider struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+func NewStaticKeyProvider(currentID string, keys map[string][]byte) *StaticKeyProvider {
+	return &StaticKeyProvider{
+		currentID: currentID,
+		keys:      keys,
+	}
+}
+
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := p.Key(p.currentID)
+	return p.currentID, key, err
+}
+
+func (p *StaticKeyProvider) Key(keyID string) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// encryptedJSONMagic prefixes encrypted payloads, it is followed by a format
+// version, the length of the key id, the key id, the nonce and the ciphertext.
+// Everything before the nonce is authenticated as additional data so the key
+// id can not be swapped.
+const encryptedJSONMagic = "\x00cje"
+
+const encryptedJSONVersion byte = 1
+
+const redacted = "[encrypted]"
+
+// EncryptedJSON is JSON[T] stored encrypted with AES-GCM in a binary column,
+// with the keys of the key ring K. The plaintext is only available through
+// Data, String and MarshalJSON are redacted so the value can not leak into
+// logs or responses.
+type EncryptedJSON[T any, K KeyRing] struct {
+	data T
+}
+
+func NewEncryptedJSON[K KeyRing, T any](data T) EncryptedJSON[T, K] {
+	return EncryptedJSON[T, K]{
+		data: data,
+	}
+}
+
+// Data return data with generic Type T
+func (j EncryptedJSON[T, K]) Data() T {
+	return j.data
+}
+
+// Value return the encrypted json, implement driver.Valuer interface
+func (j EncryptedJSON[T, K]) Value() (driver.Value, error) {
+	ba, err := json.Marshal(j.data)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := keyProviderOf[K]()
+	if err != nil {
+		return nil, err
+	}
+	return encryptJSON(provider, ba)
+}
+
+// Scan scan value into EncryptedJSON[T, K], implements sql.Scanner interface
+func (j *EncryptedJSON[T, K]) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.New("Failed to unmarshal encrypted JSON value")
+	}
+
+	provider, err := keyProviderOf[K]()
+	if err != nil {
+		return err
+	}
+	ba, err := decryptJSON(provider, raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(ba, &j.data)
+}
+
+func (j EncryptedJSON[T, K]) String() string {
+	return redacted
+}
+
+func (j EncryptedJSON[T, K]) GoString() string {
+	return redacted
+}
+
+// MarshalJSON output a placeholder instead of the plaintext, so unlike the
+// other json types the output does not round trip through UnmarshalJSON
+func (j EncryptedJSON[T, K]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+// UnmarshalJSON to deserialize the plaintext []byte, the placeholder written
+// by MarshalJSON is rejected instead of being taken as the plaintext
+func (j *EncryptedJSON[T, K]) UnmarshalJSON(b []byte) error {
+	var placeholder string
+	if json.Unmarshal(b, &placeholder) == nil && placeholder == redacted {
+		return ErrRedactedJSON
+	}
+	return json.Unmarshal(b, &j.data)
+}
+
+// GormDataType gorm common data type
+func (EncryptedJSON[T, K]) GormDataType() string {
+	return "bytes"
+}
+
+// GormDBDataType gorm db data type
+func (EncryptedJSON[T, K]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return binaryDBDataType(db)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptJSON(provider KeyProvider, plaintext []byte) ([]byte, error) {
+	keyID, key, err := provider.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(keyID) > 255 {
+		return nil, fmt.Errorf("key id %q is longer than 255 bytes", keyID)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher with key %q: %w", keyID, err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(encryptedJSONMagic)
+	buf.WriteByte(encryptedJSONVersion)
+	buf.WriteByte(byte(len(keyID)))
+	buf.WriteString(keyID)
+	header := buf.Len()
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	buf.Write(nonce)
+
+	out := buf.Bytes()
+	return gcm.Seal(out, nonce, plaintext, out[:header]), nil
+}
+
+func decryptJSON(provider KeyProvider, raw []byte) ([]byte, error) {
+	if !bytes.HasPrefix(raw, []byte(encryptedJSONMagic)) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidCiphertext)
+	}
+	rest := raw[len(encryptedJSONMagic):]
+	if len(rest) < 2 || rest[0] != encryptedJSONVersion {
+		return nil, fmt.Errorf("%w: unsupported version", ErrInvalidCiphertext)
+	}
+	keyIDLen := int(rest[1])
+	rest = rest[2:]
+	if len(rest) < keyIDLen {
+		return nil, fmt.Errorf("%w: truncated key id", ErrInvalidCiphertext)
+	}
+	keyID := string(rest[:keyIDLen])
+	rest = rest[keyIDLen:]
+	header := raw[:len(raw)-len(rest)]
+
+	key, err := provider.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher with key %q: %w", keyID, err)
+	}
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated nonce", ErrInvalidCiphertext)
+	}
+
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCiphertext, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,168 @@
+package datatype
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type integrationSecret struct {
+	Provider string `json:"provider"`
+	Token    string `json:"token"`
+}
+
+// merchantKeys and auditKeys are the key rings of the test columns, tests
+// swap the providers behind them to rotate keys
+type (
+	merchantKeys struct{}
+	auditKeys    struct{}
+)
+
+var merchantKeyProvider, auditKeyProvider KeyProvider
+
+func (merchantKeys) KeyProvider() (KeyProvider, error) { return merchantKeyProvider, nil }
+
+func (auditKeys) KeyProvider() (KeyProvider, error) { return auditKeyProvider, nil }
+
+func setKeyProvider(t *testing.T, target *KeyProvider, provider KeyProvider) {
+	*target = provider
+	t.Cleanup(func() { *target = nil })
+}
+
+type merchantSetting struct {
+	ID     uint
+	Secret EncryptedJSON[integrationSecret, merchantKeys]
+	Audit  EncryptedJSON[integrationSecret, auditKeys]
+}
+
+func TestEncryptedJSONRotation(t *testing.T) {
+	keyV1 := bytes.Repeat([]byte{1}, 32)
+	keyV2 := bytes.Repeat([]byte{2}, 32)
+	setKeyProvider(t, &merchantKeyProvider, NewStaticKeyProvider("v1", map[string][]byte{"v1": keyV1}))
+	setKeyProvider(t, &auditKeyProvider, NewStaticKeyProvider("audit", map[string][]byte{"audit": keyV1}))
+
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&merchantSetting{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	old := merchantSetting{Secret: NewEncryptedJSON[merchantKeys](integrationSecret{Provider: "shop", Token: "tok_old"})}
+	if err := db.Create(&old).Error; err != nil {
+		t.Fatalf("failed to create setting: %v", err)
+	}
+
+	merchantKeyProvider = NewStaticKeyProvider("v2", map[string][]byte{"v1": keyV1, "v2": keyV2})
+	rotated := merchantSetting{Secret: NewEncryptedJSON[merchantKeys](integrationSecret{Provider: "shop", Token: "tok_new"})}
+	if err := db.Create(&rotated).Error; err != nil {
+		t.Fatalf("failed to create setting: %v", err)
+	}
+
+	var stored, audit []byte
+	if err := db.Raw("SELECT secret, audit FROM merchant_settings WHERE id = ?", rotated.ID).Row().Scan(&stored, &audit); err != nil {
+		t.Fatalf("failed to read raw column: %v", err)
+	}
+	if bytes.Contains(stored, []byte("tok_new")) {
+		t.Fatal("expected the column not to hold the plaintext")
+	}
+	if !bytes.HasPrefix(stored, []byte(encryptedJSONMagic+"\x01\x02v2")) {
+		t.Fatalf("expected header with key id v2, got %q", stored[:8])
+	}
+	// the other column keeps the keys of its own key ring
+	if !bytes.HasPrefix(audit, []byte(encryptedJSONMagic+"\x01\x05audit")) {
+		t.Fatalf("expected header with key id audit, got %q", audit[:11])
+	}
+
+	var settings []merchantSetting
+	if err := db.Order("id").Find(&settings).Error; err != nil {
+		t.Fatalf("failed to load settings: %v", err)
+	}
+	if settings[0].Secret.Data().Token != "tok_old" || settings[1].Secret.Data().Token != "tok_new" {
+		t.Fatalf("expected both tokens to decrypt, got %s and %s", settings[0].Secret.Data().Token, settings[1].Secret.Data().Token)
+	}
+
+	merchantKeyProvider = NewStaticKeyProvider("v2", map[string][]byte{"v2": keyV2})
+	if err := db.First(&merchantSetting{}, old.ID).Error; !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected error %v for a retired key, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestEncryptedJSONTampered(t *testing.T) {
+	setKeyProvider(t, &merchantKeyProvider, NewStaticKeyProvider("v1", map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, 32),
+		"v2": bytes.Repeat([]byte{1}, 32),
+	}))
+
+	value, err := NewEncryptedJSON[merchantKeys](integrationSecret{Token: "tok"}).Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ciphertext := value.([]byte)
+
+	flipped := bytes.Clone(ciphertext)
+	flipped[len(flipped)-1] ^= 1
+	// same key material under another id, the authenticated header still rejects it
+	swapped := bytes.Clone(ciphertext)
+	swapped[len(encryptedJSONMagic)+3] = '2'
+
+	for name, raw := range map[string][]byte{
+		"flipped ciphertext": flipped,
+		"swapped key id":     swapped,
+		"plaintext":          []byte(`{"token":"tok"}`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var secret EncryptedJSON[integrationSecret, merchantKeys]
+			if err := secret.Scan(raw); !errors.Is(err, ErrInvalidCiphertext) {
+				t.Fatalf("expected error %v, got %v", ErrInvalidCiphertext, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedJSONRedacted(t *testing.T) {
+	secret := NewEncryptedJSON[merchantKeys](integrationSecret{Token: "tok_secret"})
+	setting := merchantSetting{Secret: secret}
+
+	ba, err := json.Marshal(setting)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputs := []string{
+		secret.String(),
+		fmt.Sprintf("%v", secret),
+		fmt.Sprintf("%+v", setting),
+		fmt.Sprintf("%#v", setting),
+		string(ba),
+	}
+	for _, out := range outputs {
+		if bytes.Contains([]byte(out), []byte("tok_secret")) {
+			t.Fatalf("expected plaintext to be redacted, got %s", out)
+		}
+	}
+}
+
+func TestEncryptedJSONNoKeyProvider(t *testing.T) {
+	if _, err := NewEncryptedJSON[merchantKeys](integrationSecret{Token: "tok"}).Value(); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestEncryptedJSONUnmarshal(t *testing.T) {
+	ba, err := json.Marshal(NewEncryptedJSON[merchantKeys](integrationSecret{Token: "tok_secret"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the placeholder MarshalJSON writes does not decode back
+	var secret EncryptedJSON[integrationSecret, merchantKeys]
+	if err := json.Unmarshal(ba, &secret); !errors.Is(err, ErrRedactedJSON) {
+		t.Fatalf("expected error %v, got %v", ErrRedactedJSON, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"provider":"shop","token":"tok"}`), &secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Data().Token != "tok" {
+		t.Fatalf("expected token tok, got %s", secret.Data().Token)
+	}
+}
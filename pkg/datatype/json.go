@@ -49,6 +49,9 @@ func (j *JSON[T]) Scan(value any) error {
 		} else {
 			bytes = []byte("null")
 		}
+	case nil:
+		// SQL NULL is read like a json null, use NullJSON to tell them apart
+		bytes = []byte("null")
 	default:
 		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
 	}
@@ -81,6 +84,16 @@ func (JSON[T]) GormDataType() string {
 
 // GormDBDataType gorm db data type
 func (JSON[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDBDataType(db)
+}
+
+func (js JSON[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	data, _ := js.MarshalJSON()
+	return jsonExpr(db, string(data))
+}
+
+// jsonDBDataType is the column type shared by the json column types
+func jsonDBDataType(db *gorm.DB) string {
 	switch db.Dialector.Name() {
 	case "sqlite":
 		return "JSON"
@@ -92,15 +105,14 @@ func (JSON[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
 	return ""
 }
 
-func (js JSON[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
-	data, _ := js.MarshalJSON()
-
+// jsonExpr binds data for the json column types, casting it on mysql
+func jsonExpr(db *gorm.DB, data string) clause.Expr {
 	switch db.Dialector.Name() {
 	case "mysql":
 		if v, ok := db.Dialector.(*mysql.Dialector); ok && !strings.Contains(v.ServerVersion, "MariaDB") {
-			return gorm.Expr("CAST(? AS JSON)", string(data))
+			return gorm.Expr("CAST(? AS JSON)", data)
 		}
 	}
 
-	return gorm.Expr("?", string(data))
+	return gorm.Expr("?", data)
 }
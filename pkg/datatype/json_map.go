@@ -0,0 +1,75 @@
+package datatype
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// JSONMap stores schemaless json objects. Numbers are scanned as json.Number
+// so big integers keep their precision.
+type JSONMap map[string]any
+
+// Value return json value, implement driver.Valuer interface
+func (m JSONMap) Value() (driver.Value, error) {
+	ba, err := json.Marshal(m)
+	return string(ba), err
+}
+
+// Scan scan value into JSONMap, implements sql.Scanner interface
+func (m *JSONMap) Scan(value any) error {
+	var ba []byte
+	switch v := value.(type) {
+	case []byte:
+		ba = v
+	case string:
+		ba = []byte(v)
+	case *string:
+		if v != nil {
+			ba = []byte(*v)
+		} else {
+			ba = []byte("null")
+		}
+	case nil:
+		// SQL NULL is read like a json null
+		ba = []byte("null")
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(ba))
+	dec.UseNumber()
+	var data map[string]any
+	if err := dec.Decode(&data); err != nil {
+		return err
+	}
+	*m = data
+	return nil
+}
+
+// GormDataType gorm common data type
+func (JSONMap) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType gorm db data type
+func (JSONMap) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDBDataType(db)
+}
+
+func (m JSONMap) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	data, _ := json.Marshal(m)
+	return jsonExpr(db, string(data))
+}
+
+func (m JSONMap) String() string {
+	ba, _ := json.Marshal(m)
+	return string(ba)
+}
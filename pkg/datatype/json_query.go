@@ -0,0 +1,387 @@
+package datatype
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type jsonQueryOp int
+
+const (
+	jsonQueryExtract jsonQueryOp = iota
+	jsonQueryHasKey
+	jsonQueryEquals
+	jsonQueryContains
+	jsonQueryArrayContains
+)
+
+// JSONQueryBuilder builds conditions on the fields inside a json column,
+// the SQL is chosen from the dialect of the statement the condition is used in
+//
+//	q := datatype.JSONQuery("settings")
+//	db.Where(q.HasKey("theme.color")).Where(q.Equals("theme.color", "red"))
+//
+// Paths are gjson-style dotted paths or JSON Pointers, see jsonx.ParsePath.
+// Segments made of digits address array elements.
+type JSONQueryBuilder struct {
+	column string
+}
+
+func JSONQuery(column string) JSONQueryBuilder {
+	return JSONQueryBuilder{column: column}
+}
+
+// Extract selects the value at path, as text on postgres
+func (b JSONQueryBuilder) Extract(path string) JSONQueryExpression {
+	return b.expr(jsonQueryExtract, path, nil)
+}
+
+// HasKey matches rows where path exists, including paths holding a json null
+func (b JSONQueryBuilder) HasKey(path string) JSONQueryExpression {
+	return b.expr(jsonQueryHasKey, path, nil)
+}
+
+// Equals matches rows where the scalar at path equals value
+func (b JSONQueryBuilder) Equals(path string, value any) JSONQueryExpression {
+	return b.expr(jsonQueryEquals, path, value)
+}
+
+// Contains matches rows where the document at path contains value the way
+// postgres @> does: objects match by subset of keys, arrays by subset of elements
+func (b JSONQueryBuilder) Contains(path string, value any) JSONQueryExpression {
+	return b.expr(jsonQueryContains, path, value)
+}
+
+// ArrayContains matches rows where the array at path has value as an element
+func (b JSONQueryBuilder) ArrayContains(path string, value any) JSONQueryExpression {
+	return b.expr(jsonQueryArrayContains, path, value)
+}
+
+func (b JSONQueryBuilder) expr(op jsonQueryOp, path string, value any) JSONQueryExpression {
+	segs, err := jsonx.ParsePath(path)
+	return JSONQueryExpression{column: b.column, op: op, path: segs, value: value, err: err}
+}
+
+// JSONQueryExpression implements clause.Expression
+type JSONQueryExpression struct {
+	column string
+	op     jsonQueryOp
+	path   []string
+	value  any
+	err    error
+}
+
+// Build implements clause.Expression
+func (e JSONQueryExpression) Build(builder clause.Builder) {
+	stmt, ok := builder.(*gorm.Statement)
+	if !ok {
+		return
+	}
+	if e.err != nil {
+		_ = stmt.AddError(fmt.Errorf("json query on %s: %w", e.column, e.err))
+		return
+	}
+
+	var err error
+	switch stmt.Dialector.Name() {
+	case "sqlite", "mysql":
+		err = e.buildJSONExtract(stmt)
+	case "postgres":
+		err = e.buildPostgres(stmt)
+	default:
+		err = fmt.Errorf("dialect %s is not supported", stmt.Dialector.Name())
+	}
+	if err != nil {
+		_ = stmt.AddError(fmt.Errorf("json query on %s: %w", e.column, err))
+	}
+}
+
+// buildJSONExtract builds the json_extract based SQL shared by sqlite and mysql
+func (e JSONQueryExpression) buildJSONExtract(stmt *gorm.Statement) error {
+	sqlite := stmt.Dialector.Name() == "sqlite"
+
+	switch e.op {
+	case jsonQueryExtract:
+		e.writeExtract(stmt, e.path)
+	case jsonQueryHasKey:
+		if sqlite {
+			// json_extract returns SQL NULL for a json null, json_type does not
+			stmt.WriteString("json_type(")
+			stmt.WriteQuoted(e.column)
+			stmt.WriteString(", ")
+			stmt.AddVar(stmt, jsonPath(e.path))
+			stmt.WriteString(") IS NOT NULL")
+			return nil
+		}
+		e.writeExtract(stmt, e.path)
+		stmt.WriteString(" IS NOT NULL")
+	case jsonQueryEquals:
+		value, err := scalarValue(e.value)
+		if err != nil {
+			return err
+		}
+		e.writeScalarEquals(stmt, e.path, value, sqlite)
+	case jsonQueryContains:
+		if sqlite {
+			candidate, err := decodeCandidate(e.value)
+			if err != nil {
+				return err
+			}
+			return e.writeSQLiteContains(stmt, e.path, candidate)
+		}
+		data, err := json.Marshal(e.value)
+		if err != nil {
+			return err
+		}
+		stmt.WriteString("JSON_CONTAINS(")
+		stmt.WriteQuoted(e.column)
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, string(data))
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, jsonPath(e.path))
+		stmt.WriteString(")")
+	case jsonQueryArrayContains:
+		if sqlite {
+			value, err := scalarValue(e.value)
+			if err != nil {
+				return err
+			}
+			e.writeSQLiteArrayContains(stmt, e.path, value)
+			return nil
+		}
+		data, err := json.Marshal(e.value)
+		if err != nil {
+			return err
+		}
+		stmt.WriteString("JSON_CONTAINS(")
+		e.writeExtract(stmt, e.path)
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, string(data))
+		stmt.WriteString(")")
+	}
+	return nil
+}
+
+func (e JSONQueryExpression) writeExtract(stmt *gorm.Statement, path []string) {
+	stmt.WriteString("JSON_EXTRACT(")
+	stmt.WriteQuoted(e.column)
+	stmt.WriteString(", ")
+	stmt.AddVar(stmt, jsonPath(path))
+	stmt.WriteString(")")
+}
+
+func (e JSONQueryExpression) writeScalarEquals(stmt *gorm.Statement, path []string, value any, sqlite bool) {
+	if value == nil && sqlite {
+		stmt.WriteString("json_type(")
+		stmt.WriteQuoted(e.column)
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, jsonPath(path))
+		stmt.WriteString(") = 'null'")
+		return
+	}
+	if value == nil {
+		stmt.WriteString("JSON_TYPE(")
+		e.writeExtract(stmt, path)
+		stmt.WriteString(") = 'NULL'")
+		return
+	}
+	e.writeExtract(stmt, path)
+	stmt.WriteString(" = ")
+	if _, isString := value.(string); isString || sqlite {
+		stmt.AddVar(stmt, value)
+		return
+	}
+	// mysql compares non string json scalars by their json form
+	data, _ := json.Marshal(value)
+	stmt.WriteString("CAST(")
+	stmt.AddVar(stmt, string(data))
+	stmt.WriteString(" AS JSON)")
+}
+
+func (e JSONQueryExpression) writeSQLiteArrayContains(stmt *gorm.Statement, path []string, value any) {
+	stmt.WriteString("EXISTS (SELECT 1 FROM json_each(")
+	stmt.WriteQuoted(e.column)
+	stmt.WriteString(", ")
+	stmt.AddVar(stmt, jsonPath(path))
+	if value == nil {
+		stmt.WriteString(") WHERE json_each.type = 'null')")
+		return
+	}
+	stmt.WriteString(") WHERE json_each.value = ")
+	stmt.AddVar(stmt, value)
+	stmt.WriteString(")")
+}
+
+// writeSQLiteContains emulates postgres @> as sqlite has no containment operator,
+// the candidate is flattened into conditions on its leaves
+func (e JSONQueryExpression) writeSQLiteContains(stmt *gorm.Statement, path []string, candidate any) error {
+	switch c := candidate.(type) {
+	case map[string]any:
+		stmt.WriteString("json_type(")
+		stmt.WriteQuoted(e.column)
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, jsonPath(path))
+		stmt.WriteString(") = 'object'")
+
+		keys := make([]string, 0, len(c))
+		for key := range c {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			stmt.WriteString(" AND ")
+			if err := e.writeSQLiteContains(stmt, append(path[:len(path):len(path)], key), c[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		stmt.WriteString("json_type(")
+		stmt.WriteQuoted(e.column)
+		stmt.WriteString(", ")
+		stmt.AddVar(stmt, jsonPath(path))
+		stmt.WriteString(") = 'array'")
+
+		for _, elem := range c {
+			switch elem.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("nested containers in arrays are not supported on sqlite")
+			}
+			stmt.WriteString(" AND ")
+			e.writeSQLiteArrayContains(stmt, path, elem)
+		}
+	default:
+		e.writeScalarEquals(stmt, path, candidate, true)
+	}
+	return nil
+}
+
+func (e JSONQueryExpression) buildPostgres(stmt *gorm.Statement) error {
+	switch e.op {
+	case jsonQueryExtract:
+		e.writePostgresPath(stmt, "#>>")
+	case jsonQueryHasKey:
+		e.writePostgresPath(stmt, "#>")
+		stmt.WriteString(" IS NOT NULL")
+	case jsonQueryEquals:
+		value, err := scalarValue(e.value)
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(value)
+		e.writePostgresPath(stmt, "#>")
+		stmt.WriteString(" = CAST(")
+		stmt.AddVar(stmt, string(data))
+		stmt.WriteString(" AS JSONB)")
+	case jsonQueryContains, jsonQueryArrayContains:
+		candidate := e.value
+		if e.op == jsonQueryArrayContains {
+			candidate = []any{e.value}
+		}
+		data, err := json.Marshal(candidate)
+		if err != nil {
+			return err
+		}
+		e.writePostgresPath(stmt, "#>")
+		stmt.WriteString(" @> CAST(")
+		stmt.AddVar(stmt, string(data))
+		stmt.WriteString(" AS JSONB)")
+	}
+	return nil
+}
+
+func (e JSONQueryExpression) writePostgresPath(stmt *gorm.Statement, op string) {
+	stmt.WriteString("(")
+	stmt.WriteQuoted(e.column)
+	stmt.WriteString(" " + op + " ")
+	stmt.AddVar(stmt, postgresPath(e.path))
+	stmt.WriteString(")")
+}
+
+// jsonPath formats path segments as a sqlite/mysql json path like $."theme"[0]
+func jsonPath(segs []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range segs {
+		if isIndex(seg) {
+			b.WriteString("[" + seg + "]")
+			continue
+		}
+		b.WriteString(`."`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(seg))
+		b.WriteString(`"`)
+	}
+	return b.String()
+}
+
+// postgresPath formats path segments as a postgres text array like {"theme","0"}
+func postgresPath(segs []string) string {
+	quoted := make([]string, len(segs))
+	for i, seg := range segs {
+		quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(seg) + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+func isIndex(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	_, err := strconv.ParseUint(seg, 10, 32)
+	return err == nil
+}
+
+// scalarValue normalizes value into a string, bool, int64, float64 or nil
+func scalarValue(value any) (any, error) {
+	candidate, err := decodeCandidate(value)
+	if err != nil {
+		return nil, err
+	}
+	switch candidate.(type) {
+	case map[string]any, []any:
+		return nil, fmt.Errorf("value %v is not a json scalar", value)
+	}
+	return candidate, nil
+}
+
+// decodeCandidate round-trips value through json so structs, maps and
+// jsonx values are handled alike, numbers become int64 where exact
+func decodeCandidate(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var candidate any
+	if err := dec.Decode(&candidate); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(candidate), nil
+}
+
+func normalizeNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = normalizeNumbers(elem)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = normalizeNumbers(elem)
+		}
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package datatype
+
+import (
+	"slices"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type queryTheme struct {
+	Color string   `json:"color"`
+	Tags  []string `json:"tags"`
+	Size  int      `json:"size"`
+	Dark  bool     `json:"dark"`
+	Extra *string  `json:"extra"`
+}
+
+type queryPage struct {
+	ID       uint
+	Name     string
+	Settings JSON[queryTheme]
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	return db
+}
+
+func TestJSONQuerySQLite(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&queryPage{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	extra := "x"
+	pages := []queryPage{
+		{Name: "home", Settings: NewJSON(queryTheme{Color: "red", Tags: []string{"sale", "new"}, Size: 12, Dark: true, Extra: &extra})},
+		{Name: "about", Settings: NewJSON(queryTheme{Color: "blue", Tags: []string{"new"}, Size: 14})},
+		{Name: "contact", Settings: NewJSON(queryTheme{Color: "red", Size: 14})},
+	}
+	if err := db.Create(&pages).Error; err != nil {
+		t.Fatalf("failed to create pages: %v", err)
+	}
+
+	q := JSONQuery("settings")
+	tests := []struct {
+		name     string
+		cond     JSONQueryExpression
+		expected []string
+	}{
+		{name: "has key", cond: q.HasKey("color"), expected: []string{"about", "contact", "home"}},
+		{name: "has key holding null", cond: q.HasKey("extra"), expected: []string{"about", "contact", "home"}},
+		{name: "missing key", cond: q.HasKey("font"), expected: nil},
+		{name: "equals string", cond: q.Equals("color", "red"), expected: []string{"contact", "home"}},
+		{name: "equals number", cond: q.Equals("size", 14), expected: []string{"about", "contact"}},
+		{name: "equals bool", cond: q.Equals("dark", true), expected: []string{"home"}},
+		{name: "equals null", cond: q.Equals("extra", nil), expected: []string{"about", "contact"}},
+		{name: "equals array index", cond: q.Equals("tags.0", "new"), expected: []string{"about"}},
+		{name: "array contains", cond: q.ArrayContains("tags", "new"), expected: []string{"about", "home"}},
+		{name: "contains object", cond: q.Contains("", map[string]any{"color": "red", "tags": []string{"sale"}}), expected: []string{"home"}},
+		{name: "contains array", cond: q.Contains("/tags", []string{"new"}), expected: []string{"about", "home"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			err := db.Model(&queryPage{}).Where(tt.cond).Order("name").Pluck("name", &names).Error
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	var colors []string
+	err := db.Model(&queryPage{}).Select("?", q.Extract("color")).Order("name").Pluck("color", &colors).Error
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(colors, []string{"blue", "red", "red"}) {
+		t.Fatalf("expected extracted colors, got %v", colors)
+	}
+}
+
+// dialectAs reports a different dialect name so the SQL built for other
+// databases can be checked without a server
+type dialectAs struct {
+	gorm.Dialector
+	name string
+}
+
+func (d dialectAs) Name() string {
+	return d.name
+}
+
+func TestJSONQueryDialects(t *testing.T) {
+	q := JSONQuery("settings")
+	tests := []struct {
+		dialect  string
+		cond     JSONQueryExpression
+		expected string
+		vars     []any
+	}{
+		{dialect: "mysql", cond: q.HasKey("theme.color"), expected: "JSON_EXTRACT(`settings`, ?) IS NOT NULL", vars: []any{`$."theme"."color"`}},
+		{dialect: "mysql", cond: q.Equals("size", 14), expected: "JSON_EXTRACT(`settings`, ?) = CAST(? AS JSON)", vars: []any{`$."size"`, "14"}},
+		{dialect: "mysql", cond: q.ArrayContains("tags.1", "new"), expected: "JSON_CONTAINS(JSON_EXTRACT(`settings`, ?), ?)", vars: []any{`$."tags"[1]`, `"new"`}},
+		{dialect: "postgres", cond: q.HasKey("theme.color"), expected: "(`settings` #> ?) IS NOT NULL", vars: []any{`{"theme","color"}`}},
+		{dialect: "postgres", cond: q.Equals("color", "red"), expected: "(`settings` #> ?) = CAST(? AS JSONB)", vars: []any{`{"color"}`, `"red"`}},
+		{dialect: "postgres", cond: q.Contains("", map[string]any{"color": "red"}), expected: "(`settings` #> ?) @> CAST(? AS JSONB)", vars: []any{`{}`, `{"color":"red"}`}},
+		{dialect: "postgres", cond: q.ArrayContains("tags", "new"), expected: "(`settings` #> ?) @> CAST(? AS JSONB)", vars: []any{`{"tags"}`, `["new"]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect+" "+tt.expected, func(t *testing.T) {
+			db := openSQLite(t)
+			db.Dialector = dialectAs{Dialector: db.Dialector, name: tt.dialect}
+			stmt := &gorm.Statement{DB: db}
+			tt.cond.Build(stmt)
+			if stmt.Error != nil {
+				t.Fatalf("unexpected error: %v", stmt.Error)
+			}
+			if got := stmt.SQL.String(); got != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+			if !slices.Equal(stmt.Vars, tt.vars) {
+				t.Fatalf("expected vars %v, got %v", tt.vars, stmt.Vars)
+			}
+		})
+	}
+}
+
+func TestJSONQueryInvalidPath(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&queryPage{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	var pages []queryPage
+	err := db.Where(JSONQuery("settings").HasKey("tags.#")).Find(&pages).Error
+	if err == nil {
+		t.Fatal("expected error for unsupported path")
+	}
+}
@@ -6,9 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
@@ -20,9 +18,17 @@ func NewJSONSlice[T any](s []T) JSONSlice[T] {
 	return JSONSlice[T](s)
 }
 
+// marshal writes a nil slice as [] so the column always holds an array
+func (j JSONSlice[T]) marshal() ([]byte, error) {
+	if j == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]T(j))
+}
+
 // Value return json value, implement driver.Valuer interface
 func (j JSONSlice[T]) Value() (driver.Value, error) {
-	return json.Marshal(j)
+	return j.marshal()
 }
 
 // Scan scan value into JSON[T], implements sql.Scanner interface
@@ -33,10 +39,15 @@ func (j *JSONSlice[T]) Scan(value any) error {
 		bytes = v
 	case string:
 		bytes = []byte(v)
+	case nil:
+		// SQL NULL is read as a nil slice, use NullJSONSlice to tell it from []
+		*j = nil
+		return nil
 	default:
 		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
 	}
-	return json.Unmarshal(bytes, &j)
+	// unmarshal into j itself, a json null through &j would only reset the local pointer
+	return json.Unmarshal(bytes, j)
 }
 
 // GormDataType gorm common data type
@@ -46,28 +57,12 @@ func (JSONSlice[T]) GormDataType() string {
 
 // GormDBDataType gorm db data type
 func (JSONSlice[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
-	switch db.Dialector.Name() {
-	case "sqlite":
-		return "JSON"
-	case "mysql":
-		return "JSON"
-	case "postgres":
-		return "JSONB"
-	}
-	return ""
+	return jsonDBDataType(db)
 }
 
 func (j JSONSlice[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
-	switch db.Dialector.Name() {
-	case "mysql":
-		data, _ := json.Marshal(j)
-		if v, ok := db.Dialector.(*mysql.Dialector); ok && !strings.Contains(v.ServerVersion, "MariaDB") {
-			return gorm.Expr("CAST(? AS JSON)", string(data))
-		}
-	}
-
-	data, _ := json.Marshal(j)
-	return gorm.Expr("?", string(data))
+	data, _ := j.marshal()
+	return jsonExpr(db, string(data))
 }
 
 func (j JSONSlice[T]) String() string {
@@ -0,0 +1,110 @@
+package datatype
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// JSONValue stores a jsonx.JSONValue, the raw bytes are written and read
+// back as they are without being reformatted
+type JSONValue struct {
+	data jsonx.JSONValue
+}
+
+func NewJSONValue(data jsonx.JSONValue) JSONValue {
+	return JSONValue{
+		data: data,
+	}
+}
+
+// Data return the wrapped jsonx.JSONValue
+func (j JSONValue) Data() jsonx.JSONValue {
+	return j.data
+}
+
+// raw returns the raw bytes, an empty value is stored as json null
+func (j JSONValue) raw() []byte {
+	if len(j.data.RawMessage) == 0 {
+		return []byte("null")
+	}
+	return j.data.RawMessage
+}
+
+// Value return json value, implement driver.Valuer interface
+func (j JSONValue) Value() (driver.Value, error) {
+	raw := j.raw()
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid json value: %s", raw)
+	}
+	return string(raw), nil
+}
+
+// Scan scan value into JSONValue, implements sql.Scanner interface
+func (j *JSONValue) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		// the driver may reuse the buffer after Scan returns
+		raw = bytes.Clone(v)
+	case string:
+		raw = []byte(v)
+	case *string:
+		if v != nil {
+			raw = []byte(*v)
+		} else {
+			raw = []byte("null")
+		}
+	case nil:
+		// SQL NULL is read like a json null
+		raw = []byte("null")
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+	if !json.Valid(raw) {
+		return fmt.Errorf("invalid json value: %s", raw)
+	}
+	j.data = jsonx.JSONValue{RawMessage: raw}
+	return nil
+}
+
+// MarshalJSON output the raw bytes as they are
+func (j JSONValue) MarshalJSON() ([]byte, error) {
+	return j.raw(), nil
+}
+
+// UnmarshalJSON keeps a copy of the raw bytes
+func (j *JSONValue) UnmarshalJSON(b []byte) error {
+	j.data = jsonx.JSONValue{RawMessage: bytes.Clone(b)}
+	return nil
+}
+
+func (j JSONValue) String() string {
+	return string(j.raw())
+}
+
+// GormDataType gorm common data type
+func (JSONValue) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType gorm db data type
+func (JSONValue) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDBDataType(db)
+}
+
+func (j JSONValue) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	raw := j.raw()
+	if !json.Valid(raw) {
+		_ = db.AddError(fmt.Errorf("invalid json value: %s", raw))
+	}
+	return jsonExpr(db, string(raw))
+}
@@ -0,0 +1,93 @@
+package datatype
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+type rawRecord struct {
+	ID     uint
+	Schema JSONValue
+	Extra  JSONMap
+}
+
+func TestJSONValueRoundTrip(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&rawRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	raw := `{"z": 1,  "a": [1, 2.50, 12345678901234567890], "name": "é"}`
+	record := rawRecord{
+		Schema: NewJSONValue(jsonx.JSONValue{RawMessage: json.RawMessage(raw)}),
+		Extra:  JSONMap{"id": json.Number("12345678901234567890"), "tags": []any{"a"}},
+	}
+	if err := db.Create(&record).Error; err != nil {
+		t.Fatalf("failed to create record: %v", err)
+	}
+
+	var got rawRecord
+	if err := db.First(&got, record.ID).Error; err != nil {
+		t.Fatalf("failed to load record: %v", err)
+	}
+	if got.Schema.String() != raw {
+		t.Fatalf("expected raw %s, got %s", raw, got.Schema.String())
+	}
+	data := got.Schema.Data()
+	if data.Get("name").String() != "é" {
+		t.Fatalf("expected name é, got %s", data.Get("name").String())
+	}
+	if got.Extra["id"] != json.Number("12345678901234567890") {
+		t.Fatalf("expected big id to keep its precision, got %v", got.Extra["id"])
+	}
+
+	var names []string
+	err := db.Model(&rawRecord{}).Where(JSONQuery("schema").Equals("name", "é")).Pluck("id", &names).Error
+	if err != nil || len(names) != 1 {
+		t.Fatalf("expected json query to match the record, got %v (%v)", names, err)
+	}
+}
+
+func TestJSONValueInvalid(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&rawRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	record := rawRecord{Schema: NewJSONValue(jsonx.JSONValue{RawMessage: json.RawMessage(`{"a":`)})}
+	if err := db.Create(&record).Error; err == nil {
+		t.Fatal("expected error for invalid json")
+	}
+
+	var jv JSONValue
+	if err := jv.Scan(`{"a":`); err == nil {
+		t.Fatal("expected scan error for invalid json")
+	}
+	if err := jv.Scan((*string)(nil)); err != nil || jv.String() != "null" {
+		t.Fatalf("expected nil *string to scan as null, got %s (%v)", jv.String(), err)
+	}
+}
+
+func TestJSONValueScanNull(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&rawRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := db.Exec("INSERT INTO raw_records (id, schema, extra) VALUES (?, NULL, NULL)", 1).Error; err != nil {
+		t.Fatalf("failed to insert null row: %v", err)
+	}
+
+	// SQL NULL is read like a json null
+	var got rawRecord
+	if err := db.First(&got, 1).Error; err != nil {
+		t.Fatalf("failed to load record: %v", err)
+	}
+	if got.Schema.String() != "null" {
+		t.Fatalf("expected schema null, got %s", got.Schema.String())
+	}
+	if got.Extra != nil {
+		t.Fatalf("expected a nil map, got %v", got.Extra)
+	}
+}
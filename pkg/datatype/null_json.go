@@ -0,0 +1,182 @@
+package datatype
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+/*
+NullJSON and NullJSONSlice tell SQL NULL apart from the values a json column can hold:
+
+	column      NullJSON[T]               NullJSONSlice[T]
+	SQL NULL    Valid false, zero V       Valid false, nil V
+	null        Valid true, zero V        Valid true, nil V
+	[]          -                         Valid true, empty non nil V
+
+Writing an invalid value stores SQL NULL. A valid NullJSONSlice with a nil V is
+written as [] like JSONSlice, a valid NullJSON is written as V marshals.
+In json, like sql.Null, an invalid value is encoded as null and null decodes
+into an invalid value.
+*/
+
+// NullJSON is JSON[T] that can be SQL NULL
+type NullJSON[T any] struct {
+	V     T
+	Valid bool
+}
+
+func NewNullJSON[T any](v T) NullJSON[T] {
+	return NullJSON[T]{V: v, Valid: true}
+}
+
+// Value return json value or nil for SQL NULL, implement driver.Valuer interface
+func (n NullJSON[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	ba, err := json.Marshal(n.V)
+	return string(ba), err
+}
+
+// Scan scan value into NullJSON[T], implements sql.Scanner interface
+func (n *NullJSON[T]) Scan(value any) error {
+	var zero T
+	n.V, n.Valid = zero, false
+
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	case *string:
+		// a nil pointer is SQL NULL like nil
+		if v == nil {
+			return nil
+		}
+		bytes = []byte(*v)
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+	if err := json.Unmarshal(bytes, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n NullJSON[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.V)
+}
+
+func (n *NullJSON[T]) UnmarshalJSON(b []byte) error {
+	var zero T
+	n.V, n.Valid = zero, false
+	if string(b) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(b, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// GormDataType gorm common data type
+func (NullJSON[T]) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType gorm db data type
+func (NullJSON[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDBDataType(db)
+}
+
+func (n NullJSON[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if !n.Valid {
+		return gorm.Expr("NULL")
+	}
+	data, _ := json.Marshal(n.V)
+	return jsonExpr(db, string(data))
+}
+
+// NullJSONSlice is JSONSlice[T] that can be SQL NULL
+type NullJSONSlice[T any] struct {
+	V     []T
+	Valid bool
+}
+
+func NewNullJSONSlice[T any](v []T) NullJSONSlice[T] {
+	return NullJSONSlice[T]{V: v, Valid: true}
+}
+
+// Value return json value or nil for SQL NULL, implement driver.Valuer interface
+func (n NullJSONSlice[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return JSONSlice[T](n.V).marshal()
+}
+
+// Scan scan value into NullJSONSlice[T], implements sql.Scanner interface
+func (n *NullJSONSlice[T]) Scan(value any) error {
+	n.V, n.Valid = nil, false
+	if value == nil {
+		return nil
+	}
+	var s JSONSlice[T]
+	if err := s.Scan(value); err != nil {
+		return err
+	}
+	n.V, n.Valid = s, true
+	return nil
+}
+
+func (n NullJSONSlice[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return JSONSlice[T](n.V).marshal()
+}
+
+func (n *NullJSONSlice[T]) UnmarshalJSON(b []byte) error {
+	n.V, n.Valid = nil, false
+	if string(b) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(b, &n.V); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// GormDataType gorm common data type
+func (NullJSONSlice[T]) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType gorm db data type
+func (NullJSONSlice[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDBDataType(db)
+}
+
+func (n NullJSONSlice[T]) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if !n.Valid {
+		return gorm.Expr("NULL")
+	}
+	data, _ := JSONSlice[T](n.V).marshal()
+	return jsonExpr(db, string(data))
+}
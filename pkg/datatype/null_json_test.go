@@ -0,0 +1,189 @@
+package datatype
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+)
+
+type nullPayload struct {
+	A int `json:"a"`
+}
+
+type nullRecord struct {
+	ID       uint
+	Obj      JSON[nullPayload]
+	List     JSONSlice[int]
+	NullObj  NullJSON[nullPayload]
+	NullList NullJSONSlice[int]
+}
+
+func TestNullJSONScan(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&nullRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		obj, list   any
+		objValid    bool
+		listValid   bool
+		expectedA   int
+		expectedLen int
+		nilList     bool
+	}{
+		{name: "sql null", obj: nil, list: nil, nilList: true},
+		{name: "json null", obj: "null", list: "null", objValid: true, listValid: true, nilList: true},
+		{name: "empty", obj: "{}", list: "[]", objValid: true, listValid: true},
+		{name: "value", obj: `{"a":1}`, list: "[1,2]", objValid: true, listValid: true, expectedA: 1, expectedLen: 2},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := i + 1
+			err := db.Exec(
+				"INSERT INTO null_records (id, obj, list, null_obj, null_list) VALUES (?, ?, ?, ?, ?)",
+				id, tt.obj, tt.list, tt.obj, tt.list,
+			).Error
+			if err != nil {
+				t.Fatalf("failed to insert row: %v", err)
+			}
+
+			var got nullRecord
+			if err := db.First(&got, id).Error; err != nil {
+				t.Fatalf("failed to load row: %v", err)
+			}
+			if got.Obj.Data().A != tt.expectedA || got.NullObj.V.A != tt.expectedA {
+				t.Fatalf("expected a %d, got %d and %d", tt.expectedA, got.Obj.Data().A, got.NullObj.V.A)
+			}
+			if got.NullObj.Valid != tt.objValid {
+				t.Fatalf("expected NullJSON valid %v, got %v", tt.objValid, got.NullObj.Valid)
+			}
+			if got.NullList.Valid != tt.listValid {
+				t.Fatalf("expected NullJSONSlice valid %v, got %v", tt.listValid, got.NullList.Valid)
+			}
+			if (got.List == nil) != tt.nilList || (got.NullList.V == nil) != tt.nilList {
+				t.Fatalf("expected nil list %v, got %#v and %#v", tt.nilList, got.List, got.NullList.V)
+			}
+			if len(got.List) != tt.expectedLen || len(got.NullList.V) != tt.expectedLen {
+				t.Fatalf("expected %d elements, got %d and %d", tt.expectedLen, len(got.List), len(got.NullList.V))
+			}
+		})
+	}
+}
+
+func TestNullJSONScanStringPointer(t *testing.T) {
+	raw := `{"a":1}`
+	tests := []struct {
+		name     string
+		value    *string
+		expected NullJSON[nullPayload]
+	}{
+		{name: "nil pointer", value: nil, expected: NullJSON[nullPayload]{}},
+		{name: "value", value: &raw, expected: NewNullJSON(nullPayload{A: 1})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got NullJSON[nullPayload]
+			if err := got.Scan(tt.value); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNullJSONValue(t *testing.T) {
+	db := openSQLite(t)
+	if err := db.AutoMigrate(&nullRecord{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		record   nullRecord
+		expected [4]sql.NullString
+	}{
+		{
+			name:   "zero",
+			record: nullRecord{},
+			expected: [4]sql.NullString{
+				{String: `{"a":0}`, Valid: true},
+				{String: `[]`, Valid: true},
+				{},
+				{},
+			},
+		},
+		{
+			name: "valid empty",
+			record: nullRecord{
+				List:     JSONSlice[int]{},
+				NullObj:  NewNullJSON(nullPayload{}),
+				NullList: NewNullJSONSlice[int](nil),
+			},
+			expected: [4]sql.NullString{
+				{String: `{"a":0}`, Valid: true},
+				{String: `[]`, Valid: true},
+				{String: `{"a":0}`, Valid: true},
+				{String: `[]`, Valid: true},
+			},
+		},
+		{
+			name: "value",
+			record: nullRecord{
+				Obj:      NewJSON(nullPayload{A: 1}),
+				List:     JSONSlice[int]{1},
+				NullObj:  NewNullJSON(nullPayload{A: 1}),
+				NullList: NewNullJSONSlice([]int{1}),
+			},
+			expected: [4]sql.NullString{
+				{String: `{"a":1}`, Valid: true},
+				{String: `[1]`, Valid: true},
+				{String: `{"a":1}`, Valid: true},
+				{String: `[1]`, Valid: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := tt.record
+			if err := db.Create(&record).Error; err != nil {
+				t.Fatalf("failed to create record: %v", err)
+			}
+			var got [4]sql.NullString
+			err := db.Raw("SELECT obj, list, null_obj, null_list FROM null_records WHERE id = ?", record.ID).
+				Row().Scan(&got[0], &got[1], &got[2], &got[3])
+			if err != nil {
+				t.Fatalf("failed to read columns: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNullJSONMarshal(t *testing.T) {
+	var n NullJSON[nullPayload]
+	if err := json.Unmarshal([]byte(`null`), &n); err != nil || n.Valid {
+		t.Fatalf("expected null to decode as invalid, got %+v (%v)", n, err)
+	}
+	if err := json.Unmarshal([]byte(`{"a":2}`), &n); err != nil || !n.Valid || n.V.A != 2 {
+		t.Fatalf("expected a valid value, got %+v (%v)", n, err)
+	}
+
+	var s NullJSONSlice[int]
+	ba, err := json.Marshal(s)
+	if err != nil || string(ba) != "null" {
+		t.Fatalf("expected invalid slice to encode as null, got %s (%v)", ba, err)
+	}
+	ba, err = json.Marshal(NewNullJSONSlice[int](nil))
+	if err != nil || string(ba) != "[]" {
+		t.Fatalf("expected valid nil slice to encode as [], got %s (%v)", ba, err)
+	}
+}
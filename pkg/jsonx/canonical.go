@@ -0,0 +1,184 @@
+package jsonx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tidwall/gjson"
+)
+
+var ErrNotCanonicalizable = errors.New("jsonx: value cannot be canonicalized")
+
+// Canonical returns the RFC 8785 JSON Canonicalization Scheme (JCS) form of
+// jv: no whitespace, object keys sorted by UTF-16 code units, ECMAScript
+// number formatting and minimal string escaping.
+func (jv *JSONValue) Canonical() ([]byte, error) {
+	return Canonicalize(jv.RawMessage)
+}
+
+// Canonicalize returns the RFC 8785 canonical form of a raw json document
+func Canonicalize(raw json.RawMessage) ([]byte, error) {
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("%w: invalid json", ErrNotCanonicalizable)
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, gjson.ParseBytes(raw)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns the hex encoded SHA-256 of the canonical form, so values that
+// only differ in key order, whitespace or number notation share a hash
+func (jv *JSONValue) Hash() (string, error) {
+	return Hash(jv.RawMessage)
+}
+
+// Hash returns the hex encoded SHA-256 of the canonical form of raw
+func Hash(raw json.RawMessage) (string, error) {
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, res gjson.Result) error {
+	switch res.Type {
+	case gjson.Null:
+		buf.WriteString("null")
+	case gjson.True:
+		buf.WriteString("true")
+	case gjson.False:
+		buf.WriteString("false")
+	case gjson.Number:
+		num, err := canonicalNumber(res.Num)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(num)
+	case gjson.String:
+		writeCanonicalString(buf, res.Str)
+	case gjson.JSON:
+		if res.IsArray() {
+			return writeCanonicalArray(buf, res)
+		}
+		return writeCanonicalObject(buf, res)
+	}
+	return nil
+}
+
+func writeCanonicalArray(buf *bytes.Buffer, res gjson.Result) error {
+	buf.WriteByte('[')
+	for i, ele := range res.Array() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeCanonical(buf, ele); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+func writeCanonicalObject(buf *bytes.Buffer, res gjson.Result) error {
+	type entry struct {
+		key   string
+		utf16 []uint16
+		value gjson.Result
+	}
+	var entries []entry
+	for _, m := range members(res) {
+		entries = append(entries, entry{
+			key:   m.key,
+			utf16: utf16.Encode([]rune(m.key)),
+			value: m.value,
+		})
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return slices.Compare(a.utf16, b.utf16)
+	})
+
+	buf.WriteByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			if entries[i-1].key == e.key {
+				return fmt.Errorf("%w: duplicated key %q", ErrNotCanonicalizable, e.key)
+			}
+			buf.WriteByte(',')
+		}
+		writeCanonicalString(buf, e.key)
+		buf.WriteByte(':')
+		if err := writeCanonical(buf, e.value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// canonicalNumber formats f the way ECMAScript Number.prototype.toString does
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%w: number %v", ErrNotCanonicalizable, f)
+	}
+	if f == 0 {
+		// also covers negative zero
+		return "0", nil
+	}
+	sign := ""
+	if f < 0 {
+		f, sign = -f, "-"
+	}
+	format := byte('e')
+	if f < 1e21 && f >= 1e-6 {
+		format = 'f'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	// go writes exponents with at least two digits, "1e+09" must be "1e+9"
+	if exp := strings.IndexByte(s, 'e'); exp > 0 && s[exp+2] == '0' {
+		s = s[:exp+2] + s[exp+3:]
+	}
+	return sign + s, nil
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		default:
+			buf.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.WriteByte('"')
+}
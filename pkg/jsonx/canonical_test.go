@@ -0,0 +1,176 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalNumber(t *testing.T) {
+	// cases taken from RFC 8785 appendix B
+	tests := []struct {
+		bits     uint64
+		expected string
+	}{
+		{bits: 0x0000000000000000, expected: "0"},
+		{bits: 0x8000000000000000, expected: "0"},
+		{bits: 0x0000000000000001, expected: "5e-324"},
+		{bits: 0x8000000000000001, expected: "-5e-324"},
+		{bits: 0x7fefffffffffffff, expected: "1.7976931348623157e+308"},
+		{bits: 0xffefffffffffffff, expected: "-1.7976931348623157e+308"},
+		{bits: 0x4340000000000000, expected: "9007199254740992"},
+		{bits: 0xc340000000000000, expected: "-9007199254740992"},
+		{bits: 0x4430000000000000, expected: "295147905179352830000"},
+		{bits: 0x44b52d02c7e14af5, expected: "9.999999999999997e+22"},
+		{bits: 0x44b52d02c7e14af6, expected: "1e+23"},
+		{bits: 0x44b52d02c7e14af7, expected: "1.0000000000000001e+23"},
+		{bits: 0x444b1ae4d6e2ef4e, expected: "999999999999999700000"},
+		{bits: 0x444b1ae4d6e2ef4f, expected: "999999999999999900000"},
+		{bits: 0x444b1ae4d6e2ef50, expected: "1e+21"},
+		{bits: 0x3eb0c6f7a0b5ed8c, expected: "9.999999999999997e-7"},
+		{bits: 0x3eb0c6f7a0b5ed8d, expected: "0.000001"},
+		{bits: 0x41b3de4355555553, expected: "333333333.3333332"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			got, err := canonicalNumber(math.Float64frombits(tt.bits))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		err      error
+	}{
+		{
+			// RFC 8785 section 3.2.4
+			name: "rfc example",
+			raw: `{
+				"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+				"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+				"literals": [null, true, false]
+			}`,
+			expected: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			// RFC 8785 section 3.2.3, keys are sorted by UTF-16 code units
+			name: "utf16 key order",
+			raw: `{
+				"\u20ac": "Euro Sign",
+				"\r": "Carriage Return",
+				"\ufb33": "Hebrew Letter Dalet With Dagesh",
+				"1": "One",
+				"\ud83d\ude00": "Emoji: Grinning Face",
+				"\u0080": "Control",
+				"\u00f6": "Latin Small Letter O With Diaeresis"
+			}`,
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"דּ\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "html is not escaped",
+			raw:      `"<a href=\"x\">&</a>"`,
+			expected: `"<a href=\"x\">&</a>"`,
+		},
+		{
+			name: "duplicated keys",
+			raw:  `{"a":1,"a":2}`,
+			err:  ErrNotCanonicalizable,
+		},
+		{
+			name: "invalid json",
+			raw:  `{"a":`,
+			err:  ErrNotCanonicalizable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(json.RawMessage(tt.raw))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := JSONValue{RawMessage: json.RawMessage(`{"b": [1.0, "x"], "a": {"c": null}}`)}
+	b := JSONValue{RawMessage: json.RawMessage("{\n  \"a\": {\"c\": null},\n  \"b\": [1, \"x\"]\n}")}
+	c := JSONValue{RawMessage: json.RawMessage(`{"b": [1, "y"], "a": {"c": null}}`)}
+
+	hashA, err := a.Hash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hashB, err := b.Hash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hashC, err := c.Hash()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(hashA) != 64 || strings.Trim(hashA, "0123456789abcdef") != "" {
+		t.Fatalf("expected hex sha256, got %s", hashA)
+	}
+	if hashA != hashB {
+		t.Fatalf("expected equal hashes, got %s and %s", hashA, hashB)
+	}
+	if hashA == hashC {
+		t.Fatalf("expected different hashes for different values")
+	}
+}
+
+func TestDeepEqual(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{a: `1`, b: `1.0`, expected: true},
+		{a: `1`, b: `1e0`, expected: true},
+		{a: `{"a":1,"b":2}`, b: `{ "b":2, "a":1 }`, expected: true},
+		{a: `[1,2]`, b: `[2,1]`, expected: false},
+		{a: `{"a":1}`, b: `{"a":1,"b":null}`, expected: false},
+		{a: `"1"`, b: `1`, expected: false},
+		{a: `null`, b: `false`, expected: false},
+		{a: `{"a":[{"b":"c"}]}`, b: `{"a":[{"b":"c"}]}`, expected: true},
+		{a: `9007199254740993`, b: `9007199254740992`, expected: false},
+		{a: `[9007199254740993]`, b: `[9007199254740993.0]`, expected: true},
+		{a: `0.1`, b: `1e-1`, expected: true},
+		{a: `1e99999`, b: `1e99999`, expected: true},
+		{a: `1e99999`, b: `1e99998`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a := JSONValue{RawMessage: json.RawMessage(tt.a)}
+			b := JSONValue{RawMessage: json.RawMessage(tt.b)}
+			if got := DeepEqual(a, b); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -88,3 +88,34 @@ func TestMarshalFruitsToJSON(t *testing.T) {
 
 	t.Logf("Marshalled basket JSON:\n%s", string(basketJSON))
 }
+
+type Fruit interface {
+	FruitType() string
+}
+
+func (a *Apple) FruitType() string {
+	return a.Type
+}
+
+func (w *Watermelon) FruitType() string {
+	return w.Type
+}
+
+var fruitRegistry = jsonx.NewRegistry[Fruit]("type").
+	Register("apple", func() Fruit { return &Apple{} }).
+	Register("watermelon", func() Fruit { return &Watermelon{} })
+
+func TestLoadFruitsWithRegistry(t *testing.T) {
+	var basket Basket
+	if err := json.Unmarshal(inputJSON, &basket); err != nil {
+		t.Fatalf("failed to unmarshal input JSON: %v", err)
+	}
+
+	fruits, err := jsonx.DecodeSlice(fruitRegistry, basket.Fruits)
+	if err != nil {
+		t.Fatalf("failed to decode fruits: %v", err)
+	}
+	for _, fruit := range fruits {
+		t.Logf("Loaded %s: %+v", fruit.FruitType(), fruit)
+	}
+}
@@ -0,0 +1,72 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// DeepEqual reports whether a and b hold the same json value, ignoring key
+// order and whitespace and treating numbers like 1 and 1.0 as equal. Numbers
+// are compared exactly, big integers differing beyond float64 precision differ.
+func DeepEqual(a, b JSONValue) bool {
+	return equalResult(a.Result(), b.Result())
+}
+
+// equalResult compares two json values semantically, ignoring key order and
+// whitespace. For duplicated object keys the last one wins.
+func equalResult(a, b gjson.Result) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case gjson.Null, gjson.True, gjson.False:
+		return true
+	case gjson.Number:
+		return equalNumber(a, b)
+	case gjson.String:
+		return a.Str == b.Str
+	}
+
+	if a.IsArray() != b.IsArray() || a.IsObject() != b.IsObject() {
+		return false
+	}
+	if a.IsArray() {
+		aArr, bArr := a.Array(), b.Array()
+		if len(aArr) != len(bArr) {
+			return false
+		}
+		for i := range aArr {
+			if !equalResult(aArr[i], bArr[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	aMap, bMap := a.Map(), b.Map()
+	if len(aMap) != len(bMap) {
+		return false
+	}
+	for k, av := range aMap {
+		bv, ok := bMap[k]
+		if !ok || !equalResult(av, bv) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalNumber compares two number literals exactly, literals whose exponent
+// is out of range for Decimal are compared as written
+func equalNumber(a, b gjson.Result) bool {
+	aVal := JSONValue{RawMessage: json.RawMessage(a.Raw)}
+	bVal := JSONValue{RawMessage: json.RawMessage(b.Raw)}
+	aDec, aErr := aVal.Decimal()
+	bDec, bErr := bVal.Decimal()
+	if aErr != nil || bErr != nil {
+		return strings.TrimSpace(a.Raw) == strings.TrimSpace(b.Raw)
+	}
+	return aDec.Cmp(bDec) == 0
+}
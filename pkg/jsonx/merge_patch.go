@@ -0,0 +1,110 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to jv. Keys that exist in
+// the target keep their position, new keys are appended.
+func (jv *JSONValue) MergePatch(patch JSONValue) error {
+	raw, err := mergePatchRaw(string(jv.RawMessage), patch.Result())
+	if err != nil {
+		return err
+	}
+	jv.reset(raw)
+	return nil
+}
+
+// ApplyMergePatch applies a raw JSON Merge Patch to a raw document
+func ApplyMergePatch(doc json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	jv := JSONValue{RawMessage: doc}
+	if err := jv.MergePatch(JSONValue{RawMessage: patch}); err != nil {
+		return nil, err
+	}
+	return jv.RawMessage, nil
+}
+
+func mergePatchRaw(target string, patch gjson.Result) (string, error) {
+	if !patch.IsObject() {
+		return strings.TrimSpace(patch.Raw), nil
+	}
+	if !gjson.Parse(target).IsObject() {
+		target = "{}"
+	}
+
+	var err error
+	patch.ForEach(func(key, value gjson.Result) bool {
+		segs := []string{key.String()}
+		existing, lookupErr := getRaw(target, segs)
+		if value.Type == gjson.Null {
+			if lookupErr == nil {
+				target, err = deleteRaw(target, segs)
+			}
+			return err == nil
+		}
+
+		var merged string
+		if merged, err = mergePatchRaw(existing.Raw, value); err != nil {
+			return false
+		}
+		target, err = setRaw(target, segs, merged)
+		return err == nil
+	})
+	return target, err
+}
+
+// CreateMergePatch generates a merge patch turning a into b. Merge patches
+// cannot set a value to null or patch inside arrays, use Diff for those.
+func CreateMergePatch(a, b JSONValue) JSONValue {
+	raw := createMergePatchRaw(a.Result(), b.Result())
+	return JSONValue{RawMessage: json.RawMessage(raw)}
+}
+
+func createMergePatchRaw(a, b gjson.Result) string {
+	if !a.IsObject() || !b.IsObject() {
+		return strings.TrimSpace(b.Raw)
+	}
+
+	var sb strings.Builder
+	write := func(key, raw string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(encodeKey(key))
+		sb.WriteByte(':')
+		sb.WriteString(raw)
+	}
+
+	aMap, bMap := a.Map(), b.Map()
+	written := make(map[string]struct{}, len(aMap)+len(bMap))
+	for _, m := range members(a) {
+		if _, ok := written[m.key]; ok {
+			continue
+		}
+		if _, ok := bMap[m.key]; !ok {
+			written[m.key] = struct{}{}
+			write(m.key, "null")
+		}
+	}
+	for _, m := range members(b) {
+		if _, ok := written[m.key]; ok {
+			continue
+		}
+		written[m.key] = struct{}{}
+		bv := bMap[m.key]
+		av, ok := aMap[m.key]
+		switch {
+		case !ok:
+			write(m.key, strings.TrimSpace(bv.Raw))
+		case equalResult(av, bv):
+		case av.IsObject() && bv.IsObject():
+			write(m.key, createMergePatchRaw(av, bv))
+		default:
+			write(m.key, strings.TrimSpace(bv.Raw))
+		}
+	}
+	return "{" + sb.String() + "}"
+}
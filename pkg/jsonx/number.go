@@ -0,0 +1,77 @@
+package jsonx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrNotNumber      = errors.New("jsonx: value is not a number")
+	ErrNotInteger     = errors.New("jsonx: number is not an integer")
+	ErrNumberOverflow = errors.New("jsonx: number overflows int64")
+)
+
+// maxExponent bounds the exponent of number literals decoded exactly,
+// a literal like 1e1000000000 would otherwise allocate a huge integer
+const maxExponent = 10000
+
+// NumberLiteral returns the number exactly as written in the json
+func (jv *JSONValue) NumberLiteral() (string, error) {
+	res := jv.Result()
+	if res.Type != gjson.Number {
+		return "", fmt.Errorf("%w: %s", ErrNotNumber, res.Type)
+	}
+	return strings.TrimSpace(res.Raw), nil
+}
+
+// Decimal returns the exact value of the number literal, unlike Num it does
+// not lose precision for big integers or decimal fractions
+func (jv *JSONValue) Decimal() (*big.Rat, error) {
+	literal, err := jv.NumberLiteral()
+	if err != nil {
+		return nil, err
+	}
+	if idx := strings.IndexAny(literal, "eE"); idx >= 0 {
+		exp, err := strconv.Atoi(literal[idx+1:])
+		if err != nil || exp > maxExponent || exp < -maxExponent {
+			return nil, fmt.Errorf("%w: exponent of %s out of range", ErrNotNumber, literal)
+		}
+	}
+	rat, ok := new(big.Rat).SetString(literal)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotNumber, literal)
+	}
+	return rat, nil
+}
+
+// IsInteger reports whether the value is a number without a fractional part,
+// 10, 1.0 and 1e3 are integers while 1.5 is not
+func (jv *JSONValue) IsInteger() bool {
+	rat, err := jv.Decimal()
+	if err != nil {
+		return false
+	}
+	return rat.IsInt()
+}
+
+// Int64 returns the exact integer value, numbers with a fractional part or
+// outside of the int64 range return an error instead of being truncated
+func (jv *JSONValue) Int64() (int64, error) {
+	rat, err := jv.Decimal()
+	if err != nil {
+		return 0, err
+	}
+	if !rat.IsInt() {
+		return 0, fmt.Errorf("%w: %s", ErrNotInteger, rat.FloatString(10))
+	}
+	num := rat.Num()
+	if !num.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrNumberOverflow, num.String())
+	}
+	return num.Int64(), nil
+}
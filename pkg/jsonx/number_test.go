@@ -0,0 +1,80 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestInt64(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected int64
+		err      error
+	}{
+		{name: "small", raw: `42`, expected: 42},
+		{name: "negative", raw: `-7`, expected: -7},
+		{name: "max int64", raw: `9223372036854775807`, expected: 9223372036854775807},
+		{name: "beyond float precision", raw: `9007199254740993`, expected: 9007199254740993},
+		{name: "integral fraction", raw: `3.0`, expected: 3},
+		{name: "exponent", raw: `1e3`, expected: 1000},
+		{name: "overflow", raw: `9223372036854775808`, err: ErrNumberOverflow},
+		{name: "fraction", raw: `1.5`, err: ErrNotInteger},
+		{name: "string", raw: `"1"`, err: ErrNotNumber},
+		{name: "huge exponent", raw: `1e1000000000`, err: ErrNotNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jv := JSONValue{RawMessage: json.RawMessage(tt.raw)}
+			got, err := jv.Int64()
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		raw       string
+		expected  string
+		isInteger bool
+	}{
+		{raw: `0.1`, expected: "1/10"},
+		{raw: `12345678901234567890.5`, expected: "24691357802469135781/2"},
+		{raw: `2.50`, expected: "5/2"},
+		{raw: `-1E-2`, expected: "-1/100"},
+		{raw: `100`, expected: "100", isInteger: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			jv := JSONValue{RawMessage: json.RawMessage(tt.raw)}
+			got, err := jv.Decimal()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.RatString() != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got.RatString())
+			}
+			if jv.IsInteger() != tt.isInteger {
+				t.Fatalf("expected IsInteger %v, got %v", tt.isInteger, jv.IsInteger())
+			}
+			literal, err := jv.NumberLiteral()
+			if err != nil || literal != tt.raw {
+				t.Fatalf("expected literal %s, got %s (%v)", tt.raw, literal, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrInvalidPatch    = errors.New("jsonx: invalid patch")
+	ErrPatchTestFailed = errors.New("jsonx: patch test failed")
+)
+
+type PatchOp string
+
+const (
+	PatchOpAdd     PatchOp = "add"
+	PatchOpRemove  PatchOp = "remove"
+	PatchOpReplace PatchOp = "replace"
+	PatchOpMove    PatchOp = "move"
+	PatchOpCopy    PatchOp = "copy"
+	PatchOpTest    PatchOp = "test"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation,
+// paths are JSON Pointers
+type Operation struct {
+	Op    PatchOp    `json:"op"`
+	Path  string     `json:"path"`
+	From  string     `json:"from,omitempty"`
+	Value *JSONValue `json:"value,omitempty"`
+}
+
+// UnmarshalJSON keeps an explicit `"value": null`, which encoding/json would
+// otherwise decode into a nil pointer indistinguishable from a missing value
+func (o *Operation) UnmarshalJSON(b []byte) error {
+	type operation Operation
+	var op operation
+	if err := json.Unmarshal(b, &op); err != nil {
+		return err
+	}
+	if op.Value == nil && gjson.GetBytes(b, "value").Exists() {
+		op.Value = &JSONValue{RawMessage: json.RawMessage("null")}
+	}
+	*o = Operation(op)
+	return nil
+}
+
+// Patch is an RFC 6902 JSON Patch document
+type Patch []Operation
+
+func DecodePatch(raw json.RawMessage) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return p, nil
+}
+
+// Apply applies the patch to jv atomically, jv is left untouched
+// if any of the operations fails
+func (p Patch) Apply(jv *JSONValue) error {
+	raw := string(jv.RawMessage)
+	for i, op := range p {
+		var err error
+		raw, err = op.apply(raw)
+		if err != nil {
+			return fmt.Errorf("patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	jv.reset(raw)
+	return nil
+}
+
+// ApplyPatch applies a raw JSON Patch to a raw document
+func ApplyPatch(doc json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	p, err := DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	jv := JSONValue{RawMessage: doc}
+	if err := p.Apply(&jv); err != nil {
+		return nil, err
+	}
+	return jv.RawMessage, nil
+}
+
+func (o Operation) apply(raw string) (string, error) {
+	segs, err := parsePointer(o.Path)
+	if err != nil {
+		return "", err
+	}
+
+	switch o.Op {
+	case PatchOpAdd:
+		if o.Value == nil {
+			return "", fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		return addRaw(raw, segs, string(o.Value.RawMessage))
+	case PatchOpRemove:
+		if len(segs) == 0 {
+			return "", fmt.Errorf("%w: cannot remove the root value", ErrInvalidPatch)
+		}
+		return deleteRaw(raw, segs)
+	case PatchOpReplace:
+		if o.Value == nil {
+			return "", fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		if _, err := getRaw(raw, segs); err != nil {
+			return "", err
+		}
+		return setRaw(raw, segs, string(o.Value.RawMessage))
+	case PatchOpMove, PatchOpCopy:
+		fromSegs, err := parsePointer(o.From)
+		if err != nil {
+			return "", err
+		}
+		from, err := getRaw(raw, fromSegs)
+		if err != nil {
+			return "", err
+		}
+		if o.Op == PatchOpMove {
+			if o.From == o.Path {
+				return raw, nil
+			}
+			if strings.HasPrefix(o.Path, o.From+"/") {
+				return "", fmt.Errorf("%w: cannot move %s into its own child", ErrInvalidPatch, o.From)
+			}
+			if raw, err = deleteRaw(raw, fromSegs); err != nil {
+				return "", err
+			}
+		}
+		return addRaw(raw, segs, strings.TrimSpace(from.Raw))
+	case PatchOpTest:
+		if o.Value == nil {
+			return "", fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+		actual, err := getRaw(raw, segs)
+		if err != nil {
+			return "", err
+		}
+		if !equalResult(actual, o.Value.Result()) {
+			return "", fmt.Errorf("%w: %s is %s", ErrPatchTestFailed, o.Path, actual.Raw)
+		}
+		return raw, nil
+	default:
+		return "", fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, o.Op)
+	}
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: %q is not a json pointer", ErrInvalidPath, pointer)
+	}
+	return ParsePath(pointer)
+}
+
+// addRaw implements the add semantics of RFC 6902: array members are
+// inserted rather than replaced and the parent has to exist
+func addRaw(raw string, segs []string, valueRaw string) (string, error) {
+	if len(segs) == 0 {
+		return valueRaw, nil
+	}
+	parentSegs, last := segs[:len(segs)-1], segs[len(segs)-1]
+	parent, err := getRaw(raw, parentSegs)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case parent.IsArray():
+		if last == "-" {
+			return insertRaw(raw, parentSegs, -1, valueRaw)
+		}
+		idx, err := arrayIndex(last)
+		if err != nil {
+			return "", err
+		}
+		return insertRaw(raw, parentSegs, idx, valueRaw)
+	case parent.IsObject():
+		return setRaw(raw, segs, valueRaw)
+	default:
+		return "", fmt.Errorf("%w: parent of %q is not a container", ErrPathNotFound, last)
+	}
+}
+
+// Diff generates a patch turning a into b. Unchanged members produce no
+// operations and arrays are diffed by their longest common subsequence.
+func Diff(a, b JSONValue) Patch {
+	var p Patch
+	diffResult(&p, "", a.Result(), b.Result())
+	return p
+}
+
+// DiffRaw generates a patch turning raw document a into b
+func DiffRaw(a, b json.RawMessage) Patch {
+	return Diff(JSONValue{RawMessage: a}, JSONValue{RawMessage: b})
+}
+
+func escapePointer(seg string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(seg, "~", "~0"), "/", "~1")
+}
+
+func newValue(res gjson.Result) *JSONValue {
+	raw := strings.TrimSpace(res.Raw)
+	if raw == "" {
+		raw = "null"
+	}
+	return &JSONValue{RawMessage: json.RawMessage(raw)}
+}
+
+func diffResult(p *Patch, path string, a, b gjson.Result) {
+	if equalResult(a, b) {
+		return
+	}
+	switch {
+	case a.IsObject() && b.IsObject():
+		diffObject(p, path, a, b)
+	case a.IsArray() && b.IsArray():
+		diffArray(p, path, a.Array(), b.Array())
+	default:
+		*p = append(*p, Operation{Op: PatchOpReplace, Path: path, Value: newValue(b)})
+	}
+}
+
+func diffObject(p *Patch, path string, a, b gjson.Result) {
+	aMembers, bMembers := members(a), members(b)
+	bMap := b.Map()
+	aMap := a.Map()
+
+	seen := make(map[string]struct{}, len(aMembers))
+	for _, m := range aMembers {
+		if _, ok := seen[m.key]; ok {
+			continue
+		}
+		seen[m.key] = struct{}{}
+		if _, ok := bMap[m.key]; !ok {
+			*p = append(*p, Operation{Op: PatchOpRemove, Path: path + "/" + escapePointer(m.key)})
+		}
+	}
+	seen = make(map[string]struct{}, len(bMembers))
+	for _, m := range bMembers {
+		if _, ok := seen[m.key]; ok {
+			continue
+		}
+		seen[m.key] = struct{}{}
+		childPath := path + "/" + escapePointer(m.key)
+		av, ok := aMap[m.key]
+		if !ok {
+			*p = append(*p, Operation{Op: PatchOpAdd, Path: childPath, Value: newValue(bMap[m.key])})
+			continue
+		}
+		diffResult(p, childPath, av, bMap[m.key])
+	}
+}
+
+func diffArray(p *Patch, path string, a, b []gjson.Result) {
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if equalResult(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// walk forward, the patched array is always b[:j] followed by a[i:],
+	// so j is the index the next operation applies to
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		childPath := path + "/" + strconv.Itoa(j)
+		switch {
+		case i < len(a) && j < len(b) && equalResult(a[i], b[j]):
+			i, j = i+1, j+1
+		case i < len(a) && j < len(b) && lcs[i+1][j+1] == lcs[i][j]:
+			// substituting keeps the common subsequence, diff in place
+			diffResult(p, childPath, a[i], b[j])
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			*p = append(*p, Operation{Op: PatchOpAdd, Path: childPath, Value: newValue(b[j])})
+			j++
+		default:
+			*p = append(*p, Operation{Op: PatchOpRemove, Path: childPath})
+			i++
+		}
+	}
+}
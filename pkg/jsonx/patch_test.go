@@ -0,0 +1,259 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	// cases taken from the examples of RFC 6902 appendix A
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "add object member",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/baz","value":"qux"}]`,
+			expected: `{"foo":"bar","baz":"qux"}`,
+		},
+		{
+			name:     "add array element",
+			doc:      `{"foo":["bar","baz"]}`,
+			patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:     "remove object member",
+			doc:      `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"remove","path":"/baz"}]`,
+			expected: `{"foo":"bar"}`,
+		},
+		{
+			name:     "remove array element",
+			doc:      `{"foo":["bar","qux","baz"]}`,
+			patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			expected: `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:     "replace value",
+			doc:      `{"baz":"qux","foo":"bar"}`,
+			patch:    `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			expected: `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:     "move value",
+			doc:      `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:     "move array element",
+			doc:      `{"foo":["all","grass","cows","eat"]}`,
+			patch:    `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			expected: `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:     "copy value",
+			doc:      `{"foo":{"bar":1}}`,
+			patch:    `[{"op":"copy","from":"/foo","path":"/baz"}]`,
+			expected: `{"foo":{"bar":1},"baz":{"bar":1}}`,
+		},
+		{
+			name:     "test success",
+			doc:      `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch:    `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			expected: `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:  "test failure",
+			doc:   `{"baz":"qux"}`,
+			patch: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			err:   ErrPatchTestFailed,
+		},
+		{
+			name:  "test big integer failure",
+			doc:   `{"id":9007199254740993}`,
+			patch: `[{"op":"test","path":"/id","value":9007199254740992}]`,
+			err:   ErrPatchTestFailed,
+		},
+		{
+			name:     "add null value",
+			doc:      `{"foo":"bar"}`,
+			patch:    `[{"op":"add","path":"/child","value":null}]`,
+			expected: `{"foo":"bar","child":null}`,
+		},
+		{
+			name:     "append with dash",
+			doc:      `{"foo":["bar"]}`,
+			patch:    `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			expected: `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:  "add to missing parent",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "replace missing member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"qux"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "missing value",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unknown op",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"merge","path":"/baz","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ApplyPatch(json.RawMessage(tt.doc), json.RawMessage(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(res) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, res)
+			}
+		})
+	}
+}
+
+func TestPatchApplyIsAtomic(t *testing.T) {
+	jv := JSONValue{RawMessage: json.RawMessage(`{"foo":"bar"}`)}
+	p, err := DecodePatch(json.RawMessage(`[{"op":"add","path":"/baz","value":1},{"op":"remove","path":"/missing"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Apply(&jv); !errors.Is(err, ErrPathNotFound) {
+		t.Fatalf("expected error %v, got %v", ErrPathNotFound, err)
+	}
+	if string(jv.RawMessage) != `{"foo":"bar"}` {
+		t.Fatalf("expected document untouched, got %s", jv.RawMessage)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// cases taken from RFC 7396 appendix A
+	tests := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, expected: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, expected: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, expected: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, expected: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, expected: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `null`, expected: `null`},
+		{target: `{"e":null}`, patch: `{"a":1}`, expected: `{"e":null,"a":1}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, expected: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, expected: `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target+" "+tt.patch, func(t *testing.T) {
+			res, err := ApplyMergePatch(json.RawMessage(tt.target), json.RawMessage(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(res) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, res)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		numOps  int
+		firstOp PatchOp
+	}{
+		{name: "equal", a: `{"a":1,"b":[1,2]}`, b: `{"b":[1,2.0],"a":1}`, numOps: 0},
+		{name: "nested replace", a: `{"a":{"b":1,"c":2}}`, b: `{"a":{"b":1,"c":3}}`, numOps: 1, firstOp: PatchOpReplace},
+		{name: "remove and add", a: `{"a":1}`, b: `{"b":1}`, numOps: 2, firstOp: PatchOpRemove},
+		{name: "array insert", a: `["x","y","z"]`, b: `["x","new","y","z"]`, numOps: 1, firstOp: PatchOpAdd},
+		{name: "array remove", a: `["x","y","z"]`, b: `["x","z"]`, numOps: 1, firstOp: PatchOpRemove},
+		{name: "array reorder", a: `["x","y","z"]`, b: `["z","x","y"]`, numOps: 2},
+		{name: "array element change", a: `[{"id":"a","v":1},{"id":"b"}]`, b: `[{"id":"a","v":2},{"id":"b"}]`, numOps: 1, firstOp: PatchOpReplace},
+		{name: "type change", a: `{"a":[1]}`, b: `{"a":{"0":1}}`, numOps: 1, firstOp: PatchOpReplace},
+		{name: "escaped keys", a: `{"a/b":1,"c~d":1}`, b: `{"a/b":2,"c~d":2}`, numOps: 2, firstOp: PatchOpReplace},
+		{name: "big integer change", a: `{"id":9007199254740993}`, b: `{"id":9007199254740992}`, numOps: 1, firstOp: PatchOpReplace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DiffRaw(json.RawMessage(tt.a), json.RawMessage(tt.b))
+			if len(p) != tt.numOps {
+				t.Fatalf("expected %d operations, got %d: %+v", tt.numOps, len(p), p)
+			}
+			if tt.numOps > 0 && tt.firstOp != "" && p[0].Op != tt.firstOp {
+				t.Fatalf("expected first op %s, got %s", tt.firstOp, p[0].Op)
+			}
+
+			// the generated patch has to survive a round trip and turn a into b
+			encoded, err := json.Marshal(p)
+			if err != nil {
+				t.Fatalf("failed to marshal patch: %v", err)
+			}
+			res, err := ApplyPatch(json.RawMessage(tt.a), encoded)
+			if err != nil {
+				t.Fatalf("failed to apply patch %s: %v", encoded, err)
+			}
+			resJV, bJV := JSONValue{RawMessage: res}, JSONValue{RawMessage: json.RawMessage(tt.b)}
+			if !equalResult(resJV.Result(), bJV.Result()) {
+				t.Fatalf("expected %s, got %s", tt.b, res)
+			}
+		})
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	a := JSONValue{RawMessage: json.RawMessage(`{"a":1,"b":{"c":1,"d":2},"e":[1]}`)}
+	b := JSONValue{RawMessage: json.RawMessage(`{"b":{"c":1,"d":3},"e":[1,2],"f":"new"}`)}
+
+	patch := CreateMergePatch(a, b)
+	if string(patch.RawMessage) != `{"a":null,"b":{"d":3},"e":[1,2],"f":"new"}` {
+		t.Fatalf("unexpected merge patch %s", patch.RawMessage)
+	}
+	if err := a.MergePatch(patch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !equalResult(a.Result(), b.Result()) {
+		t.Fatalf("expected %s, got %s", b.RawMessage, a.RawMessage)
+	}
+
+	// integers beyond float64 precision still differ
+	bigA := JSONValue{RawMessage: json.RawMessage(`{"id":9007199254740993}`)}
+	bigB := JSONValue{RawMessage: json.RawMessage(`{"id":9007199254740992}`)}
+	if patch := CreateMergePatch(bigA, bigB); string(patch.RawMessage) != `{"id":9007199254740992}` {
+		t.Fatalf("unexpected merge patch %s", patch.RawMessage)
+	}
+}
@@ -0,0 +1,362 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	ErrInvalidPath  = errors.New("jsonx: invalid path")
+	ErrPathNotFound = errors.New("jsonx: path not found")
+)
+
+// isAppendToken reports whether seg appends to the end of an array,
+// "-" is defined by RFC 6901 and "-1" is the sjson convention
+func isAppendToken(seg string) bool {
+	return seg == "-" || seg == "-1"
+}
+
+// ParsePath splits a path into unescaped segments. A path starting with "/"
+// is treated as an RFC 6901 JSON Pointer, otherwise as a gjson-style dotted
+// path. Queries, wildcards and modifiers are not supported for mutation.
+// An empty path refers to the whole document.
+func ParsePath(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(path, "/") {
+		segs := strings.Split(path[1:], "/")
+		for i, seg := range segs {
+			if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(seg, "~0", ""), "~1", ""), "~") {
+				return nil, fmt.Errorf("%w: bad escape in json pointer %q", ErrInvalidPath, path)
+			}
+			segs[i] = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		}
+		return segs, nil
+	}
+
+	var (
+		segs []string
+		curr strings.Builder
+	)
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 >= len(path) {
+				return nil, fmt.Errorf("%w: dangling escape in %q", ErrInvalidPath, path)
+			}
+			i++
+			curr.WriteByte(path[i])
+		case '.':
+			segs = append(segs, curr.String())
+			curr.Reset()
+		case '*', '?', '#', '|', '@':
+			return nil, fmt.Errorf("%w: unsupported character %q in %q", ErrInvalidPath, c, path)
+		default:
+			curr.WriteByte(c)
+		}
+	}
+	segs = append(segs, curr.String())
+	return segs, nil
+}
+
+// Set sets value at path, creating missing parent objects on the way.
+// Existing keys keep their position, new keys are appended to their object.
+func (jv *JSONValue) Set(path string, value any) error {
+	segs, valueRaw, err := prepareMutation(path, value)
+	if err != nil {
+		return err
+	}
+	raw, err := setRaw(string(jv.RawMessage), segs, valueRaw)
+	if err != nil {
+		return fmt.Errorf("set %q: %w", path, err)
+	}
+	jv.reset(raw)
+	return nil
+}
+
+// Delete removes the object key or array element at path.
+func (jv *JSONValue) Delete(path string) error {
+	segs, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		return fmt.Errorf("%w: cannot delete the root value", ErrInvalidPath)
+	}
+	raw, err := deleteRaw(string(jv.RawMessage), segs)
+	if err != nil {
+		return fmt.Errorf("delete %q: %w", path, err)
+	}
+	jv.reset(raw)
+	return nil
+}
+
+// Insert inserts value into the array at path before index, shifting later
+// elements. An index equal to the array length or -1 appends.
+func (jv *JSONValue) Insert(path string, index int, value any) error {
+	segs, valueRaw, err := prepareMutation(path, value)
+	if err != nil {
+		return err
+	}
+	raw, err := insertRaw(string(jv.RawMessage), segs, index, valueRaw)
+	if err != nil {
+		return fmt.Errorf("insert %q: %w", path, err)
+	}
+	jv.reset(raw)
+	return nil
+}
+
+// reset replaces the raw message and keeps the cached result consistent
+func (jv *JSONValue) reset(raw string) {
+	res := gjson.Parse(raw)
+	jv.RawMessage = json.RawMessage(raw)
+	jv.res = &res
+}
+
+func prepareMutation(path string, value any) ([]string, string, error) {
+	segs, err := ParsePath(path)
+	if err != nil {
+		return nil, "", err
+	}
+	valueRaw, err := marshalRaw(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return segs, valueRaw, nil
+}
+
+func marshalRaw(value any) (string, error) {
+	switch v := value.(type) {
+	case JSONValue:
+		if len(v.RawMessage) == 0 {
+			return "null", nil
+		}
+		return string(v.RawMessage), nil
+	case *JSONValue:
+		if v == nil || len(v.RawMessage) == 0 {
+			return "null", nil
+		}
+		return string(v.RawMessage), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// member is an object member or array element with its offsets in the raw json
+type member struct {
+	key   string
+	start int // start of the key for objects, of the value for arrays
+	value gjson.Result
+}
+
+func (m member) end() int {
+	return m.value.Index + len(m.value.Raw)
+}
+
+func members(res gjson.Result) []member {
+	var ms []member
+	res.ForEach(func(key, value gjson.Result) bool {
+		m := member{start: value.Index, value: value}
+		if res.IsObject() {
+			m.key = key.String()
+			m.start = key.Index
+		}
+		ms = append(ms, m)
+		return true
+	})
+	return ms
+}
+
+// lookup finds the member addressed by seg, returning its position
+func lookup(res gjson.Result, ms []member, seg string) (int, error) {
+	if res.IsObject() {
+		for i, m := range ms {
+			if m.key == seg {
+				return i, nil
+			}
+		}
+		return -1, nil
+	}
+	if res.IsArray() {
+		idx, err := arrayIndex(seg)
+		if err != nil {
+			return -1, err
+		}
+		if idx >= len(ms) {
+			return -1, nil
+		}
+		return idx, nil
+	}
+	return -1, fmt.Errorf("%w: cannot traverse %s value with %q", ErrInvalidPath, res.Type, seg)
+}
+
+func arrayIndex(seg string) (int, error) {
+	// RFC 6901 forbids leading zeros
+	if seg == "" || (len(seg) > 1 && seg[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPath, seg)
+	}
+	idx, err := strconv.Atoi(seg)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrInvalidPath, seg)
+	}
+	return idx, nil
+}
+
+func splice(raw string, start, end int, repl string) string {
+	return raw[:start] + repl + raw[end:]
+}
+
+// appendMember appends an encoded member to a non-empty or empty container
+func appendMember(raw string, res gjson.Result, ms []member, encoded string) string {
+	if len(ms) > 0 {
+		last := ms[len(ms)-1]
+		return splice(raw, last.end(), last.end(), ","+encoded)
+	}
+	// empty container, keep the brackets and drop inner whitespace
+	closing := res.Index + len(strings.TrimRight(res.Raw, " \t\r\n")) - 1
+	return splice(raw, res.Index+1, closing, encoded)
+}
+
+func encodeKey(key string) string {
+	b, _ := json.Marshal(key)
+	return string(b)
+}
+
+// buildRaw builds the nested value for segs that do not exist yet
+func buildRaw(segs []string, valueRaw string) string {
+	for i := len(segs) - 1; i >= 0; i-- {
+		if isAppendToken(segs[i]) {
+			valueRaw = "[" + valueRaw + "]"
+		} else {
+			valueRaw = "{" + encodeKey(segs[i]) + ":" + valueRaw + "}"
+		}
+	}
+	return valueRaw
+}
+
+func setRaw(raw string, segs []string, valueRaw string) (string, error) {
+	if len(segs) == 0 {
+		return valueRaw, nil
+	}
+	res := gjson.Parse(raw)
+	if !res.Exists() {
+		return buildRaw(segs, valueRaw), nil
+	}
+	ms := members(res)
+	seg := segs[0]
+
+	if res.IsArray() && isAppendToken(seg) {
+		return appendMember(raw, res, ms, buildRaw(segs[1:], valueRaw)), nil
+	}
+	idx, err := lookup(res, ms, seg)
+	if err != nil {
+		return "", err
+	}
+	if idx < 0 {
+		if res.IsArray() {
+			// only appending right after the last element is allowed
+			if i, _ := arrayIndex(seg); i == len(ms) {
+				return appendMember(raw, res, ms, buildRaw(segs[1:], valueRaw)), nil
+			}
+			return "", fmt.Errorf("%w: array index %s out of range", ErrPathNotFound, seg)
+		}
+		return appendMember(raw, res, ms, encodeKey(seg)+":"+buildRaw(segs[1:], valueRaw)), nil
+	}
+
+	m := ms[idx]
+	child, err := setRaw(m.value.Raw, segs[1:], valueRaw)
+	if err != nil {
+		return "", err
+	}
+	return splice(raw, m.value.Index, m.end(), child), nil
+}
+
+func deleteRaw(raw string, segs []string) (string, error) {
+	res := gjson.Parse(raw)
+	ms := members(res)
+	idx, err := lookup(res, ms, segs[0])
+	if err != nil {
+		return "", err
+	}
+	if idx < 0 {
+		return "", fmt.Errorf("%w: %q", ErrPathNotFound, segs[0])
+	}
+
+	m := ms[idx]
+	if len(segs) > 1 {
+		child, err := deleteRaw(m.value.Raw, segs[1:])
+		if err != nil {
+			return "", err
+		}
+		return splice(raw, m.value.Index, m.end(), child), nil
+	}
+
+	switch {
+	case idx+1 < len(ms):
+		// drop the member together with the following comma
+		return splice(raw, m.start, ms[idx+1].start, ""), nil
+	case idx > 0:
+		// last member, drop the preceding comma
+		return splice(raw, ms[idx-1].end(), m.end(), ""), nil
+	default:
+		return splice(raw, m.start, m.end(), ""), nil
+	}
+}
+
+func insertRaw(raw string, segs []string, index int, valueRaw string) (string, error) {
+	res := gjson.Parse(raw)
+	if len(segs) > 0 {
+		ms := members(res)
+		idx, err := lookup(res, ms, segs[0])
+		if err != nil {
+			return "", err
+		}
+		if idx < 0 {
+			return "", fmt.Errorf("%w: %q", ErrPathNotFound, segs[0])
+		}
+		m := ms[idx]
+		child, err := insertRaw(m.value.Raw, segs[1:], index, valueRaw)
+		if err != nil {
+			return "", err
+		}
+		return splice(raw, m.value.Index, m.end(), child), nil
+	}
+
+	if !res.IsArray() {
+		return "", fmt.Errorf("%w: cannot insert into %s value", ErrInvalidPath, res.Type)
+	}
+	ms := members(res)
+	switch {
+	case index == -1 || index == len(ms):
+		return appendMember(raw, res, ms, valueRaw), nil
+	case index < 0 || index > len(ms):
+		return "", fmt.Errorf("%w: array index %d out of range", ErrPathNotFound, index)
+	default:
+		return splice(raw, ms[index].start, ms[index].start, valueRaw+","), nil
+	}
+}
+
+func getRaw(raw string, segs []string) (gjson.Result, error) {
+	res := gjson.Parse(raw)
+	for _, seg := range segs {
+		ms := members(res)
+		idx, err := lookup(res, ms, seg)
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		if idx < 0 {
+			return gjson.Result{}, fmt.Errorf("%w: %q", ErrPathNotFound, seg)
+		}
+		res = gjson.Parse(ms[idx].value.Raw)
+	}
+	return res, nil
+}
@@ -0,0 +1,175 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []string
+		err      error
+	}{
+		{path: "", expected: nil},
+		{path: "/a/b/0", expected: []string{"a", "b", "0"}},
+		{path: "/a~1b/c~0d", expected: []string{"a/b", "c~d"}},
+		{path: "/", expected: []string{""}},
+		{path: "/a~2", err: ErrInvalidPath},
+		{path: "a.b.0", expected: []string{"a", "b", "0"}},
+		{path: `a\.b.c`, expected: []string{"a.b", "c"}},
+		{path: "a.*", err: ErrInvalidPath},
+		{path: "a.#", err: ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := ParsePath(tt.path)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(segs, tt.expected) {
+				t.Fatalf("expected %q, got %q", tt.expected, segs)
+			}
+		})
+	}
+}
+
+func TestJSONValueSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		path     string
+		value    any
+		expected string
+		err      error
+	}{
+		{name: "replace keeps order", raw: `{"b":1,"a":2,"c":3}`, path: "a", value: 5, expected: `{"b":1,"a":5,"c":3}`},
+		{name: "replace by pointer", raw: `{"b":1,"a":{"x":[1,2]}}`, path: "/a/x/1", value: "y", expected: `{"b":1,"a":{"x":[1,"y"]}}`},
+		{name: "add key at end", raw: `{"b":1}`, path: "a", value: true, expected: `{"b":1,"a":true}`},
+		{name: "add key to empty object", raw: `{ }`, path: "a", value: 1, expected: `{"a":1}`},
+		{name: "create parents", raw: `{"b":1}`, path: "x.y", value: 1, expected: `{"b":1,"x":{"y":1}}`},
+		{name: "append by pointer", raw: `[1, 2]`, path: "/-", value: 3, expected: `[1, 2,3]`},
+		{name: "append by sjson index", raw: `{"a":[]}`, path: "a.-1", value: 3, expected: `{"a":[3]}`},
+		{name: "append at length", raw: `[1]`, path: "1", value: 2, expected: `[1,2]`},
+		{name: "escaped key", raw: `{"a/b":1}`, path: "/a~1b", value: 2, expected: `{"a/b":2}`},
+		{name: "replace root", raw: `{"a":1}`, path: "", value: []int{1}, expected: `[1]`},
+		{name: "keeps formatting", raw: "{\n  \"a\": 1,\n  \"b\": 2\n}", path: "b", value: 3, expected: "{\n  \"a\": 1,\n  \"b\": 3\n}"},
+		{name: "json value", raw: `{"a":1}`, path: "a", value: *NewBool(true), expected: `{"a":true}`},
+		{name: "index out of range", raw: `[1]`, path: "/3", value: 1, err: ErrPathNotFound},
+		{name: "traverse scalar", raw: `{"a":1}`, path: "a.b", value: 1, err: ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jv := JSONValue{RawMessage: json.RawMessage(tt.raw)}
+			// warm up the cached result to make sure it gets refreshed
+			_ = jv.Result()
+
+			err := jv.Set(tt.path, tt.value)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				if string(jv.RawMessage) != tt.raw {
+					t.Fatalf("expected value untouched on error, got %s", jv.RawMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(jv.RawMessage) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, jv.RawMessage)
+			}
+			if jv.Result().Raw != tt.expected {
+				t.Fatalf("expected cached result %s, got %s", tt.expected, jv.Result().Raw)
+			}
+		})
+	}
+}
+
+func TestJSONValueDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		path     string
+		expected string
+		err      error
+	}{
+		{name: "first key", raw: `{"a":1, "b":2, "c":3}`, path: "a", expected: `{"b":2, "c":3}`},
+		{name: "middle key", raw: `{"a":1, "b":2, "c":3}`, path: "b", expected: `{"a":1, "c":3}`},
+		{name: "last key", raw: `{"a":1, "b":2, "c":3}`, path: "/c", expected: `{"a":1, "b":2}`},
+		{name: "only key", raw: `{"a":1}`, path: "a", expected: `{}`},
+		{name: "array element", raw: `{"a":[1,2,3]}`, path: "/a/1", expected: `{"a":[1,3]}`},
+		{name: "missing key", raw: `{"a":1}`, path: "b", err: ErrPathNotFound},
+		{name: "root", raw: `{"a":1}`, path: "", err: ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jv := JSONValue{RawMessage: json.RawMessage(tt.raw)}
+			err := jv.Delete(tt.path)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(jv.RawMessage) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, jv.RawMessage)
+			}
+			if !json.Valid(jv.RawMessage) {
+				t.Fatalf("expected valid json, got %s", jv.RawMessage)
+			}
+		})
+	}
+}
+
+func TestJSONValueInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		path     string
+		index    int
+		expected string
+		err      error
+	}{
+		{name: "front", raw: `{"a":[1,2]}`, path: "a", index: 0, expected: `{"a":["x",1,2]}`},
+		{name: "middle", raw: `{"a":[1,2]}`, path: "/a", index: 1, expected: `{"a":[1,"x",2]}`},
+		{name: "end", raw: `{"a":[1,2]}`, path: "a", index: 2, expected: `{"a":[1,2,"x"]}`},
+		{name: "append", raw: `[]`, path: "", index: -1, expected: `["x"]`},
+		{name: "out of range", raw: `[1]`, path: "", index: 3, err: ErrPathNotFound},
+		{name: "not an array", raw: `{"a":{}}`, path: "a", index: 0, err: ErrInvalidPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jv := JSONValue{RawMessage: json.RawMessage(tt.raw)}
+			err := jv.Insert(tt.path, tt.index, "x")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(jv.RawMessage) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, jv.RawMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrUnexpectedToken = errors.New("jsonx: unexpected token")
+
+// Stream walks a json document token by token, so large documents can be
+// processed piece by piece instead of being held in memory as a whole.
+// Callbacks of EachMember and EachElement have to consume exactly one value
+// with Decode, Value, Skip or another nested Each call.
+type Stream struct {
+	dec  *json.Decoder
+	path []string
+}
+
+func NewStream(r io.Reader) *Stream {
+	return &Stream{dec: json.NewDecoder(r)}
+}
+
+// Path returns the JSON Pointer of the value being walked
+func (s *Stream) Path() string {
+	if len(s.path) == 0 {
+		return ""
+	}
+	escaped := make([]string, len(s.path))
+	for i, seg := range s.path {
+		escaped[i] = escapePointer(seg)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+func (s *Stream) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s: %w", s.Path(), fmt.Errorf(format, args...))
+}
+
+func (s *Stream) expectDelim(expected json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return s.errorf("%w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != expected {
+		return s.errorf("%w: expected %v, got %v", ErrUnexpectedToken, expected, tok)
+	}
+	return nil
+}
+
+// EachMember walks an object, fn is called with every key and must consume its value
+func (s *Stream) EachMember(fn func(key string) error) error {
+	if err := s.expectDelim('{'); err != nil {
+		return err
+	}
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return s.errorf("%w", err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return s.errorf("%w: expected object key, got %v", ErrUnexpectedToken, tok)
+		}
+		s.path = append(s.path, key)
+		err = fn(key)
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	return s.expectDelim('}')
+}
+
+// EachElement walks an array, fn is called with every index and must consume its value
+func (s *Stream) EachElement(fn func(index int) error) error {
+	if err := s.expectDelim('['); err != nil {
+		return err
+	}
+	for i := 0; s.dec.More(); i++ {
+		s.path = append(s.path, strconv.Itoa(i))
+		err := fn(i)
+		s.path = s.path[:len(s.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	return s.expectDelim(']')
+}
+
+// Decode unmarshals the next value into v
+func (s *Stream) Decode(v any) error {
+	if err := s.dec.Decode(v); err != nil {
+		return s.errorf("%w", err)
+	}
+	return nil
+}
+
+// Value reads the next value as a JSONValue
+func (s *Stream) Value() (JSONValue, error) {
+	var raw json.RawMessage
+	if err := s.Decode(&raw); err != nil {
+		return JSONValue{}, err
+	}
+	return JSONValue{RawMessage: raw}, nil
+}
+
+// Skip discards the next value without decoding it
+func (s *Stream) Skip() error {
+	depth := 0
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return s.errorf("%w", err)
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package jsonx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	input := `{
+		"name": "page",
+		"ignored": {"deep": [1, {"x": [true]}]},
+		"items": [{"id": "a", "v": 1}, {"id": "b", "v": 2}],
+		"last": null
+	}`
+
+	var (
+		name string
+		ids  []string
+		keys []string
+	)
+	s := NewStream(strings.NewReader(input))
+	err := s.EachMember(func(key string) error {
+		keys = append(keys, key)
+		switch key {
+		case "name":
+			return s.Decode(&name)
+		case "items":
+			return s.EachElement(func(index int) error {
+				item, err := s.Value()
+				if err != nil {
+					return err
+				}
+				ids = append(ids, item.Get("id").String())
+				return nil
+			})
+		default:
+			return s.Skip()
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "page" {
+		t.Fatalf("expected name page, got %s", name)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("expected ids a,b, got %v", ids)
+	}
+	if strings.Join(keys, ",") != "name,ignored,items,last" {
+		t.Fatalf("expected all keys to be walked, got %v", keys)
+	}
+}
+
+func TestStreamErrorPath(t *testing.T) {
+	input := `{"items": [{"v": 1}, {"v": "x"}]}`
+
+	s := NewStream(strings.NewReader(input))
+	err := s.EachMember(func(key string) error {
+		return s.EachElement(func(index int) error {
+			var item struct {
+				V int `json:"v"`
+			}
+			return s.Decode(&item)
+		})
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.HasPrefix(err.Error(), "/items/1: ") {
+		t.Fatalf("expected error at /items/1, got %v", err)
+	}
+}
+
+func TestStreamUnexpectedToken(t *testing.T) {
+	s := NewStream(strings.NewReader(`[1, 2]`))
+	err := s.EachMember(func(key string) error {
+		return s.Skip()
+	})
+	if !errors.Is(err, ErrUnexpectedToken) {
+		t.Fatalf("expected error %v, got %v", ErrUnexpectedToken, err)
+	}
+}
@@ -0,0 +1,111 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownDiscriminator = errors.New("jsonx: unknown discriminator")
+
+// Registry decodes a discriminated union: the value of the discriminator key
+// selects the constructor of the concrete type, e.g. {"type": "text", ...}
+type Registry[T any] struct {
+	discriminator string
+	constructors  map[string]func() T
+}
+
+func NewRegistry[T any](discriminator string) *Registry[T] {
+	return &Registry[T]{
+		discriminator: discriminator,
+		constructors:  make(map[string]func() T),
+	}
+}
+
+// Register binds a discriminator value to a constructor, the constructor has
+// to return a pointer so the raw json can be unmarshaled into it
+func (r *Registry[T]) Register(typ string, constructor func() T) *Registry[T] {
+	r.constructors[typ] = constructor
+	return r
+}
+
+// Types returns the registered discriminator values in sorted order
+func (r *Registry[T]) Types() []string {
+	types := make([]string, 0, len(r.constructors))
+	for typ := range r.constructors {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// DecodeError reports which item failed to decode, Path is a JSON Pointer
+// relative to the decoded value or slice
+type DecodeError struct {
+	Path string
+	Type string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("decode")
+	if e.Path != "" {
+		sb.WriteString(" " + e.Path)
+	}
+	if e.Type != "" {
+		sb.WriteString(" (" + e.Type + ")")
+	}
+	sb.WriteString(": " + e.Err.Error())
+	return sb.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode decodes jv into the concrete type selected by its discriminator
+func Decode[T any](r *Registry[T], jv JSONValue) (T, error) {
+	var zero T
+
+	discriminator := jv.Get(r.discriminator)
+	if !discriminator.IsString() {
+		return zero, &DecodeError{
+			Err: fmt.Errorf("%w: %q is %s, expected a string", ErrUnknownDiscriminator, r.discriminator, discriminator.Result().Type),
+		}
+	}
+	typ := discriminator.String()
+	constructor, ok := r.constructors[typ]
+	if !ok {
+		return zero, &DecodeError{
+			Type: typ,
+			Err:  fmt.Errorf("%w: %s %q, expected one of %s", ErrUnknownDiscriminator, r.discriminator, typ, strings.Join(r.Types(), ", ")),
+		}
+	}
+
+	v := constructor()
+	if err := json.Unmarshal(jv.RawMessage, v); err != nil {
+		return zero, &DecodeError{Type: typ, Err: err}
+	}
+	return v, nil
+}
+
+// DecodeSlice decodes every item of jvs, errors carry the index of the bad item
+func DecodeSlice[T any](r *Registry[T], jvs []JSONValue) ([]T, error) {
+	res := make([]T, 0, len(jvs))
+	for i, jv := range jvs {
+		v, err := Decode(r, jv)
+		if err != nil {
+			var decodeErr *DecodeError
+			if errors.As(err, &decodeErr) {
+				decodeErr.Path = "/" + strconv.Itoa(i) + decodeErr.Path
+			}
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
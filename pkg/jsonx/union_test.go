@@ -0,0 +1,122 @@
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type shape interface {
+	area() float64
+}
+
+type square struct {
+	Side float64 `json:"side"`
+}
+
+func (s *square) area() float64 {
+	return s.Side * s.Side
+}
+
+type rect struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (r *rect) area() float64 {
+	return r.Width * r.Height
+}
+
+func newShapeRegistry() *Registry[shape] {
+	return NewRegistry[shape]("kind").
+		Register("square", func() shape { return &square{} }).
+		Register("rect", func() shape { return &rect{} })
+}
+
+func TestDecode(t *testing.T) {
+	r := newShapeRegistry()
+
+	s, err := Decode(r, JSONValue{RawMessage: json.RawMessage(`{"kind":"rect","width":2,"height":3}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.(*rect); !ok {
+		t.Fatalf("expected *rect, got %T", s)
+	}
+	if s.area() != 6 {
+		t.Fatalf("expected area 6, got %v", s.area())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	r := newShapeRegistry()
+
+	tests := []struct {
+		name    string
+		raw     string
+		err     error
+		message string
+	}{
+		{
+			name:    "unknown discriminator",
+			raw:     `{"kind":"circle"}`,
+			err:     ErrUnknownDiscriminator,
+			message: `decode (circle): jsonx: unknown discriminator: kind "circle", expected one of rect, square`,
+		},
+		{
+			name:    "missing discriminator",
+			raw:     `{"side":1}`,
+			err:     ErrUnknownDiscriminator,
+			message: `"kind" is Null, expected a string`,
+		},
+		{
+			name:    "bad payload",
+			raw:     `{"kind":"square","side":"big"}`,
+			message: `decode (square): json: cannot unmarshal string`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(r, JSONValue{RawMessage: json.RawMessage(tt.raw)})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Fatalf("expected error to contain %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestDecodeSlice(t *testing.T) {
+	r := newShapeRegistry()
+
+	var items []JSONValue
+	if err := json.Unmarshal([]byte(`[{"kind":"square","side":2},{"kind":"rect","width":1,"height":2}]`), &items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	shapes, err := DecodeSlice(r, items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shapes) != 2 || shapes[0].area() != 4 || shapes[1].area() != 2 {
+		t.Fatalf("unexpected shapes %+v", shapes)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"kind":"square","side":2},{"kind":"triangle"}]`), &items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = DecodeSlice(r, items)
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected DecodeError, got %v", err)
+	}
+	if decodeErr.Path != "/1" || decodeErr.Type != "triangle" {
+		t.Fatalf("expected bad item /1 (triangle), got %s (%s)", decodeErr.Path, decodeErr.Type)
+	}
+}
@@ -6,8 +6,11 @@ import (
 )
 
 type BlocksSchema struct {
-	Type     string                  `json:"type"`
-	Name     field.TranslatableField `json:"name"`
-	Limit    *uint8                  `json:"limit,omitempty"`
-	Elements []jsonx.JSONValue       `json:"elements,omitempty"`
+	Type      string                  `json:"type"`
+	Name      field.TranslatableField `json:"name"`
+	Limit     *uint8                  `json:"limit,omitempty"`
+	MaxBlocks *uint8                  `json:"max_blocks,omitempty"`
+	Blocks    []BlocksSchema          `json:"blocks,omitempty"`
+	Elements  []jsonx.JSONValue       `json:"elements,omitempty"`
+	Rules     []ValidationRule        `json:"rules,omitempty"`
 }
@@ -0,0 +1,17 @@
+package json
+
+type ComponentMigration struct {
+	Version uint32          `json:"version"`
+	Steps   []MigrationStep `json:"steps"`
+}
+
+type MigrationStep struct {
+	Op        string            `json:"op"`
+	BlockType string            `json:"block_type,omitempty"`
+	Element   string            `json:"element,omitempty"`
+	To        string            `json:"to,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+	Min       *int64            `json:"min,omitempty"`
+	Max       *int64            `json:"max,omitempty"`
+	Cases     map[string]string `json:"cases,omitempty"`
+}
@@ -0,0 +1,19 @@
+package json
+
+import (
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+)
+
+type ComponentPreset struct {
+	ID              string                     `json:"id"`
+	Name            field.TranslatableField    `json:"name"`
+	ElementSettings map[string]jsonx.JSONValue `json:"element_settings,omitempty"`
+	Blocks          []PresetBlock              `json:"blocks,omitempty"`
+}
+
+type PresetBlock struct {
+	Type            string                     `json:"type"`
+	ElementSettings map[string]jsonx.JSONValue `json:"element_settings,omitempty"`
+	Blocks          []PresetBlock              `json:"blocks,omitempty"`
+}
@@ -6,8 +6,12 @@ import (
 )
 
 type ComponentSchema struct {
-	Name      field.TranslatableField `json:"name"`
-	MaxBlocks *uint8                  `json:"max_blocks,omitempty"`
-	Blocks    []BlocksSchema          `json:"blocks,omitempty"`
-	Elements  []jsonx.JSONValue       `json:"elements"`
+	Name       field.TranslatableField `json:"name"`
+	Version    uint32                  `json:"version,omitempty"`
+	MaxBlocks  *uint8                  `json:"max_blocks,omitempty"`
+	Blocks     []BlocksSchema          `json:"blocks,omitempty"`
+	Elements   []jsonx.JSONValue       `json:"elements"`
+	Presets    []ComponentPreset       `json:"presets,omitempty"`
+	Migrations []ComponentMigration    `json:"migrations,omitempty"`
+	Rules      []ValidationRule        `json:"rules,omitempty"`
 }
@@ -0,0 +1,15 @@
+package json
+
+type PageTypeSchema struct {
+	Name               string            `json:"name"`
+	AllowedComponents  []string          `json:"allowed_components,omitempty"`
+	RequiredComponents []string          `json:"required_components,omitempty"`
+	MaxInstances       map[string]uint8  `json:"max_instances,omitempty"`
+	StaticComponents   []StaticComponent `json:"static_components,omitempty"`
+}
+
+type StaticComponent struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
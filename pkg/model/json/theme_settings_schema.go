@@ -0,0 +1,16 @@
+package json
+
+import (
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+)
+
+type ThemeSettingsSchema struct {
+	Categories []ThemeSettingsCategory `json:"categories"`
+}
+
+type ThemeSettingsCategory struct {
+	ID       string                  `json:"id"`
+	Name     field.TranslatableField `json:"name"`
+	Elements []jsonx.JSONValue       `json:"elements"`
+}
@@ -0,0 +1,9 @@
+package json
+
+import "github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+
+type ValidationRule struct {
+	ID      string                  `json:"id"`
+	Expr    string                  `json:"expr"`
+	Message field.TranslatableField `json:"message"`
+}
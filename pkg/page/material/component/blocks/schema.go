@@ -5,40 +5,128 @@ import (
 
 	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
+// MaxDepth limits the nesting of blocks, blocks directly in a component are at depth 1
+const MaxDepth = 3
+
+// Schema declares a block type. Blocks declares the child block types allowed
+// inside it, Limit of a child applies per parent block and MaxBlocks limits
+// the number of children of a single block.
 type Schema struct {
-	Type     string            `json:"type"`
-	Name     string            `json:"name"`
-	Limit    *uint8            `json:"limit,omitempty"`
-	Elements []element.Element `json:"elements,omitempty"`
+	Type      string                  `json:"type"`
+	Name      field.TranslatableField `json:"name"`
+	Limit     *uint8                  `json:"limit,omitempty"`
+	MaxBlocks *uint8                  `json:"max_blocks,omitempty"`
+	Blocks    []Schema                `json:"blocks,omitempty"`
+	Elements  []element.Element       `json:"elements,omitempty"`
+	// Rules validate several element values of a block together
+	Rules []element.Rule `json:"rules,omitempty"`
+
+	visibility  *element.Visibility
+	ruleChecker *element.Rules
 }
 
+// Parse parses the raw block schema without localizing it,
+// use Localize to get a copy for a specific locale.
 func Parse(
 	raw jsonmodel.BlocksSchema,
-	locale string,
-	localeProvider locale.LocaleProvider,
 ) (*Schema, error) {
-	elements := make([]element.Element, 0, len(raw.Elements))
-	for _, rawEle := range raw.Elements {
-		ele, err := element.UnmarshalElement(rawEle)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal element: %w", err)
-		}
-		elements = append(elements, ele)
+	return parse(raw, 1)
+}
+
+func parse(raw jsonmodel.BlocksSchema, depth int) (*Schema, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("block %s exceeds max depth of %d", raw.Type, MaxDepth)
+	}
+	elements, err := element.UnmarshalElements(raw.Elements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal element: %w", err)
 	}
 	for _, ele := range elements {
-		ele.SetLocale(locale, localeProvider)
 		if err := ele.Validate(); err != nil {
 			return nil, fmt.Errorf("element %s(%s) validation failed: %w", ele.GetID(), ele.EleType(), err)
 		}
 	}
-	raw.Name.SetLocale(locale, localeProvider)
+	visibility, err := element.NewVisibility(elements)
+	if err != nil {
+		return nil, fmt.Errorf("invalid visible_if: %w", err)
+	}
+	children := make([]Schema, 0, len(raw.Blocks))
+	for _, rawChild := range raw.Blocks {
+		child, err := parse(rawChild, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse child block schema of %s: %w", raw.Type, err)
+		}
+		children = append(children, *child)
+	}
+	rules := make([]element.Rule, 0, len(raw.Rules))
+	for _, rawRule := range raw.Rules {
+		rules = append(rules, element.Rule(rawRule))
+	}
+	ruleChecker, err := element.NewRules(rules, visibility)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
 	return &Schema{
-		Name:     raw.Name.String(),
-		Limit:    raw.Limit,
-		Type:     raw.Type,
-		Elements: elements,
+		Name:        raw.Name,
+		Limit:       raw.Limit,
+		MaxBlocks:   raw.MaxBlocks,
+		Type:        raw.Type,
+		Blocks:      children,
+		Elements:    elements,
+		Rules:       rules,
+		visibility:  visibility,
+		ruleChecker: ruleChecker,
 	}, nil
 }
+
+// Localize returns a copy of the schema with all translatable fields
+// resolved for the given locale, the receiver is left untouched.
+func (s *Schema) Localize(
+	locale string,
+	localeProvider locale.LocaleProvider,
+) *Schema {
+	elements := make([]element.Element, 0, len(s.Elements))
+	for _, ele := range s.Elements {
+		elements = append(elements, ele.Localize(locale, localeProvider))
+	}
+	children := make([]Schema, 0, len(s.Blocks))
+	for _, child := range s.Blocks {
+		children = append(children, *child.Localize(locale, localeProvider))
+	}
+	rules := make([]element.Rule, 0, len(s.Rules))
+	for _, rule := range s.Rules {
+		rule.Message = rule.Message.Localize(locale, localeProvider)
+		rules = append(rules, rule)
+	}
+	return &Schema{
+		Name:        s.Name.Localize(locale, localeProvider),
+		Limit:       s.Limit,
+		MaxBlocks:   s.MaxBlocks,
+		Type:        s.Type,
+		Blocks:      children,
+		Elements:    elements,
+		Rules:       rules,
+		visibility:  s.visibility.Localize(locale, localeProvider),
+		ruleChecker: s.ruleChecker.Localize(locale, localeProvider),
+	}
+}
+
+// Visibility returns the visible_if conditions of the elements parsed by Parse
+func (s *Schema) Visibility() *element.Visibility {
+	return s.visibility
+}
+
+// RuleChecker returns the rules parsed by Parse
+func (s *Schema) RuleChecker() *element.Rules {
+	return s.ruleChecker
+}
+
+// IsElementVisible reports whether element id is visible with the settings of
+// the block according to visible_if
+func (s *Schema) IsElementVisible(id string, settings Settings) bool {
+	return s.visibility.IsVisible(id, settings.ElementSettings)
+}
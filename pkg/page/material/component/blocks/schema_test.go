@@ -0,0 +1,46 @@
+package blocks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+)
+
+func TestParseDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name: "max depth",
+			raw:  `{"type": "a", "name": "a", "blocks": [{"type": "b", "name": "b", "blocks": [{"type": "c", "name": "c"}]}]}`,
+		},
+		{
+			name:     "too deep",
+			raw:      `{"type": "a", "name": "a", "blocks": [{"type": "b", "name": "b", "blocks": [{"type": "c", "name": "c", "blocks": [{"type": "d", "name": "d"}]}]}]}`,
+			expected: "block d exceeds max depth of 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw jsonmodel.BlocksSchema
+			if err := json.Unmarshal([]byte(tt.raw), &raw); err != nil {
+				t.Fatalf("failed to unmarshal block schema: %v", err)
+			}
+			_, err := Parse(raw)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -6,6 +6,11 @@ type Settings struct {
 	Type            string                     `json:"type"`
 	ID              string                     `json:"id"`
 	ElementSettings map[string]jsonx.JSONValue `json:"element_settings,omitempty"`
+	BlockOrder      []string                   `json:"block_order,omitempty"`
+	Blocks          map[string]Settings        `json:"blocks,omitempty"`
+	// Disabled blocks are validated and kept but not rendered,
+	// they do not count towards the block limits
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 func (s *Settings) GetSettingByID(id string) *jsonx.JSONValue {
@@ -0,0 +1,454 @@
+package element
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+// Condition is a parsed visible_if expression. It compares sibling element
+// values with literals, e.g.
+//
+//	show_overlay == true && overlay_style != "none"
+//
+// Operands are element ids, strings in single or double quotes, numbers,
+// true, false and null. The operators are + - * / on numbers, == != < <= >
+// >= && || ! and parentheses. A bare element id is true when its value is
+// true, a non zero number or a non empty string.
+type Condition struct {
+	expr string
+	root conditionNode
+	ids  []string
+}
+
+// ParseCondition parses a visible_if expression
+func ParseCondition(expr string) (*Condition, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	ids := make([]string, 0)
+	for _, tok := range tokens {
+		if tok.kind != tokenIdent || slices.Contains(conditionKeywords, tok.text) {
+			continue
+		}
+		if !slices.Contains(ids, tok.text) {
+			ids = append(ids, tok.text)
+		}
+	}
+	return &Condition{expr: expr, root: root, ids: ids}, nil
+}
+
+func (c *Condition) String() string {
+	return c.expr
+}
+
+// IDs returns the element ids referenced by the condition
+func (c *Condition) IDs() []string {
+	return c.ids
+}
+
+// Eval evaluates the condition, lookup returns the value of an element id
+func (c *Condition) Eval(lookup func(id string) (jsonx.JSONValue, bool)) bool {
+	return truthy(c.root.eval(lookup))
+}
+
+type conditionNode interface {
+	eval(lookup func(id string) (jsonx.JSONValue, bool)) any
+}
+
+type (
+	literalNode struct{ val any }
+	identNode   struct{ id string }
+	notNode     struct{ operand conditionNode }
+	logicNode   struct {
+		op          string
+		left, right conditionNode
+	}
+	compareNode struct {
+		op          string
+		left, right conditionNode
+	}
+	arithmeticNode struct {
+		op          string
+		left, right conditionNode
+	}
+)
+
+func (n literalNode) eval(func(string) (jsonx.JSONValue, bool)) any {
+	return n.val
+}
+
+// eval converts the value of the element to nil, bool, string or *big.Rat
+func (n identNode) eval(lookup func(string) (jsonx.JSONValue, bool)) any {
+	val, ok := lookup(n.id)
+	if !ok || len(val.RawMessage) == 0 {
+		return nil
+	}
+	switch {
+	case val.IsBool():
+		return val.Bool()
+	case val.IsString():
+		return val.String()
+	case val.IsNumber():
+		num, err := val.Decimal()
+		if err != nil {
+			return nil
+		}
+		return num
+	}
+	return nil
+}
+
+func (n notNode) eval(lookup func(string) (jsonx.JSONValue, bool)) any {
+	return !truthy(n.operand.eval(lookup))
+}
+
+func (n logicNode) eval(lookup func(string) (jsonx.JSONValue, bool)) any {
+	left := truthy(n.left.eval(lookup))
+	if n.op == "&&" {
+		return left && truthy(n.right.eval(lookup))
+	}
+	return left || truthy(n.right.eval(lookup))
+}
+
+// eval compares operands of the same type, operands of different types are
+// only unequal and never ordered
+func (n compareNode) eval(lookup func(string) (jsonx.JSONValue, bool)) any {
+	left, right := n.left.eval(lookup), n.right.eval(lookup)
+	cmp, comparable := compareValues(left, right)
+	switch n.op {
+	case "==":
+		return comparable && cmp == 0
+	case "!=":
+		return !comparable || cmp != 0
+	}
+	if !comparable || left == nil {
+		return false
+	}
+	if _, ok := left.(bool); ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// eval computes with numbers only, any other operand or a division by zero
+// results in null
+func (n arithmeticNode) eval(lookup func(string) (jsonx.JSONValue, bool)) any {
+	left, ok := n.left.eval(lookup).(*big.Rat)
+	if !ok {
+		return nil
+	}
+	right, ok := n.right.eval(lookup).(*big.Rat)
+	if !ok {
+		return nil
+	}
+	result := new(big.Rat)
+	switch n.op {
+	case "+":
+		return result.Add(left, right)
+	case "-":
+		return result.Sub(left, right)
+	case "*":
+		return result.Mul(left, right)
+	}
+	if right.Sign() == 0 {
+		return nil
+	}
+	return result.Quo(left, right)
+}
+
+func compareValues(left, right any) (int, bool) {
+	switch l := left.(type) {
+	case nil:
+		return 0, right == nil
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return 0, false
+		}
+		if l == r {
+			return 0, true
+		}
+		return 1, true
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(l, r), true
+	case *big.Rat:
+		r, ok := right.(*big.Rat)
+		if !ok {
+			return 0, false
+		}
+		return l.Cmp(r), true
+	}
+	return 0, false
+}
+
+func truthy(val any) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case *big.Rat:
+		return v.Sign() != 0
+	}
+	return false
+}
+
+var conditionKeywords = []string{"true", "false", "null"}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type conditionToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+	tokens := make([]conditionToken, 0)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, conditionToken{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenNumber, text: string(runes[i:end])})
+			i = end
+		case r == '_' || unicode.IsLetter(r):
+			end := i + 1
+			for end < len(runes) && (runes[end] == '_' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: tokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "+", "-", "*", "/"} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", r, i)
+			}
+			tokens = append(tokens, conditionToken{kind: tokenOp, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) peekOp(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOp {
+		return "", false
+	}
+	if !slices.Contains(ops, p.tokens[p.pos].text) {
+		return "", false
+	}
+	return p.tokens[p.pos].text, true
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOp("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	if _, ok := p.peekOp("!"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOp("==", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *conditionParser) parseSum() (conditionNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *conditionParser) parseTerm() (conditionNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp("*", "/")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *conditionParser) parseOperand() (conditionNode, error) {
+	if _, ok := p.peekOp("("); ok {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOp(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	}
+	negative := false
+	if _, ok := p.peekOp("-"); ok {
+		p.pos++
+		negative = true
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	if negative && tok.kind != tokenNumber {
+		return nil, fmt.Errorf("unexpected - before %s", tok.text)
+	}
+	switch tok.kind {
+	case tokenString:
+		return literalNode{val: tok.text}, nil
+	case tokenNumber:
+		num, ok := new(big.Rat).SetString(tok.text)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %s", tok.text)
+		}
+		if negative {
+			num.Neg(num)
+		}
+		return literalNode{val: num}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return literalNode{val: true}, nil
+		case "false":
+			return literalNode{val: false}, nil
+		case "null":
+			return literalNode{val: nil}, nil
+		}
+		return identNode{id: tok.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", tok.text)
+}
@@ -0,0 +1,122 @@
+package element
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+func TestConditionEval(t *testing.T) {
+	settings := map[string]jsonx.JSONValue{
+		"show_overlay":  {RawMessage: json.RawMessage(`true`)},
+		"overlay_style": {RawMessage: json.RawMessage(`"dark"`)},
+		"opacity":       {RawMessage: json.RawMessage(`0.5`)},
+		"title":         {RawMessage: json.RawMessage(`""`)},
+		"extra":         {RawMessage: json.RawMessage(`null`)},
+	}
+	lookup := func(id string) (jsonx.JSONValue, bool) {
+		val, ok := settings[id]
+		return val, ok
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `show_overlay`, expected: true},
+		{expr: `!show_overlay`, expected: false},
+		{expr: `show_overlay == true`, expected: true},
+		{expr: `overlay_style == 'dark'`, expected: true},
+		{expr: `overlay_style != "dark"`, expected: false},
+		{expr: `opacity > 0.25 && opacity <= 0.5`, expected: true},
+		{expr: `opacity >= 1 || overlay_style == "light"`, expected: false},
+		{expr: `!(opacity < 0.5) && show_overlay`, expected: true},
+		{expr: `title`, expected: false},
+		{expr: `extra == null`, expected: true},
+		{expr: `missing == null`, expected: true},
+		{expr: `missing`, expected: false},
+		{expr: `overlay_style == 1`, expected: false},
+		{expr: `overlay_style != 1`, expected: true},
+		{expr: `overlay_style > 1`, expected: false},
+		{expr: `opacity * 2 == 1`, expected: true},
+		{expr: `(opacity + 0.5) > 0.9`, expected: true},
+		{expr: `opacity - 1 < 0 && -1 < opacity`, expected: true},
+		{expr: `opacity / 0 == null`, expected: true},
+		{expr: `opacity / 0 < 1`, expected: false},
+		{expr: `overlay_style + 1 == null`, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, err := ParseCondition(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cond.Eval(lookup); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseConditionInvalid(t *testing.T) {
+	tests := []string{
+		``,
+		`show_overlay ==`,
+		`(show_overlay`,
+		`show_overlay = true`,
+		`title == "open`,
+		`a b`,
+		`opacity +`,
+		`-title`,
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCondition(expr); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	elements := func(visibleIf map[string]string) []Element {
+		return []Element{
+			&Select{ID: "show_overlay", Type: "select", Default: "no", VisibleIf: visibleIf["show_overlay"]},
+			&Range{ID: "overlay_opacity", Type: "range", Max: 100, Default: 50, VisibleIf: visibleIf["overlay_opacity"]},
+			&Range{ID: "overlay_blur", Type: "range", Max: 10, VisibleIf: visibleIf["overlay_blur"]},
+		}
+	}
+
+	visibility, err := NewVisibility(elements(map[string]string{
+		"overlay_opacity": `show_overlay == "yes"`,
+		"overlay_blur":    `overlay_opacity > 0`,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	hidden := map[string]jsonx.JSONValue{}
+	if visibility.IsVisible("overlay_opacity", hidden) {
+		t.Fatal("expected overlay_opacity to be hidden by the default of show_overlay")
+	}
+	if visibility.IsVisible("overlay_blur", hidden) {
+		t.Fatal("expected overlay_blur to be hidden along with overlay_opacity")
+	}
+	shown := map[string]jsonx.JSONValue{"show_overlay": {RawMessage: json.RawMessage(`"yes"`)}}
+	if !visibility.IsVisible("overlay_blur", shown) {
+		t.Fatal("expected overlay_blur to be visible")
+	}
+
+	if _, err := NewVisibility(elements(map[string]string{"overlay_blur": `overlay_size > 0`})); err == nil {
+		t.Fatal("expected error for unknown reference")
+	}
+	if _, err := NewVisibility(elements(map[string]string{
+		"show_overlay":    `overlay_blur > 0`,
+		"overlay_opacity": `show_overlay == "yes"`,
+		"overlay_blur":    `overlay_opacity > 0`,
+	})); err == nil {
+		t.Fatal("expected error for cyclic references")
+	}
+}
@@ -1,9 +1,6 @@
 package element
 
 import (
-	"encoding/json"
-	"fmt"
-
 	"github.com/leeseika/cv-demo/pkg/jsonx"
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 	"github.com/osteele/liquid/values"
@@ -19,43 +16,36 @@ const (
 
 type Element interface {
 	GetID() string
+	// GetVisibleIf returns the visible_if condition, empty when always visible
+	GetVisibleIf() string
 	EleType() ElementType
 	Validate() error
-	SetLocale(locale string, provider locale.LocaleProvider)
+	Localize(locale string, provider locale.LocaleProvider) Element
 	CheckValue(val jsonx.JSONValue) (jsonx.JSONValue, error)
 	GetDefault() jsonx.JSONValue
 	ToLiquid(val jsonx.JSONValue) (values.Value, error)
 }
 
+// ValueFormatter is implemented by elements whose values need locale aware
+// formatting before being displayed, e.g. a range with a unit.
+type ValueFormatter interface {
+	FormatValue(val jsonx.JSONValue, formatter *locale.Formatter) (string, error)
+}
+
+var elementRegistry = jsonx.NewRegistry[Element]("type").
+	Register(string(ElementTypeText), func() Element { return &Text{} }).
+	Register(string(ElementTypeRange), func() Element { return &Range{} }).
+	Register(string(ElementTypeSelect), func() Element { return &Select{} })
+
 func UnmarshalElement(
 	rawEle jsonx.JSONValue,
 ) (Element, error) {
-	eleTypeJV := rawEle.Get("type")
-	if !eleTypeJV.IsString() {
-		return nil, fmt.Errorf("unexpected element jsonx type %s", eleTypeJV.Result().Type)
-	}
-
-	eleType := eleTypeJV.String()
-	switch ElementType(eleType) {
-	case ElementTypeText:
-		var text Text
-		if err := json.Unmarshal(rawEle.RawMessage, &text); err != nil {
-			return nil, err
-		}
-		return &text, nil
-	case ElementTypeRange:
-		var rg Range
-		if err := json.Unmarshal(rawEle.RawMessage, &rg); err != nil {
-			return nil, err
-		}
-		return &rg, nil
-	case ElementTypeSelect:
-		var sel Select
-		if err := json.Unmarshal(rawEle.RawMessage, &sel); err != nil {
-			return nil, err
-		}
-		return &sel, nil
-	default:
-		return nil, fmt.Errorf("unsupported element type %s", eleType)
-	}
+	return jsonx.Decode(elementRegistry, rawEle)
+}
+
+// UnmarshalElements unmarshals a list of elements, errors carry the index of the bad element
+func UnmarshalElements(
+	rawEles []jsonx.JSONValue,
+) ([]Element, error) {
+	return jsonx.DecodeSlice(elementRegistry, rawEles)
 }
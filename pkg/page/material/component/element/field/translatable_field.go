@@ -7,33 +7,38 @@ import (
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
+// TranslatableField keeps the raw translatable value, which is either a
+// "t:" prefixed locale key or an object keyed by locale.
 type TranslatableField struct {
 	jsonx.JSONValue
 }
 
-func (t *TranslatableField) SetLocale(locale string, provider locale.LocaleProvider) {
+// Localize returns a copy of the field resolved for the given locale,
+// the receiver keeps all of its translations.
+func (t TranslatableField) Localize(locale string, provider locale.LocaleProvider) TranslatableField {
 	if t.IsString() {
 		if provider == nil {
-			return
+			return t
 		}
 		labelStr := t.String()
 		if !strings.HasPrefix(labelStr, "t:") {
-			return
+			return t
 		}
 		contextKey := strings.TrimPrefix(labelStr, "t:")
 		localizedLabel := provider.Get(contextKey)
 		if !localizedLabel.IsString() {
-			return
+			return t
 		}
-		t.JSONValue = localizedLabel
+		return TranslatableField{JSONValue: localizedLabel}
 	} else if t.IsObject() {
 		localizedLabel := t.Get(locale)
 		if !localizedLabel.IsString() {
 			localizedLabel = t.Get("default")
 		}
 		if !localizedLabel.IsString() {
-			return
+			return t
 		}
-		t.JSONValue = localizedLabel
+		return TranslatableField{JSONValue: localizedLabel}
 	}
+	return t
 }
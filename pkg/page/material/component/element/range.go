@@ -1,7 +1,9 @@
 package element
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
 
 	"github.com/leeseika/cv-demo/pkg/jsonx"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
@@ -10,37 +12,68 @@ import (
 )
 
 type Range struct {
-	ID      string                  `json:"id"`
-	Type    string                  `json:"type"`
-	Max     int64                   `json:"max"`
-	Min     int64                   `json:"min"`
-	Default int64                   `json:"default"`
-	Unit    string                  `json:"unit"`
-	Label   field.TranslatableField `json:"label"`
+	ID          string                  `json:"id"`
+	Type        string                  `json:"type"`
+	Max         int64                   `json:"max"`
+	Min         int64                   `json:"min"`
+	Default     int64                   `json:"default"`
+	Step        json.Number             `json:"step,omitempty"`
+	IntegerOnly bool                    `json:"integer_only,omitempty"`
+	Unit        string                  `json:"unit"`
+	Label       field.TranslatableField `json:"label"`
+	VisibleIf   string                  `json:"visible_if,omitempty"`
 }
 
 func (r *Range) GetID() string {
 	return r.ID
 }
 
+func (r *Range) GetVisibleIf() string {
+	return r.VisibleIf
+}
+
 func (r *Range) EleType() ElementType {
 	return ElementTypeRange
 }
 
 func (r *Range) GetDefault() jsonx.JSONValue {
-	return *jsonx.NewNumber(float64(r.Default))
+	return *jsonx.NewNumber(r.Default)
 }
 
 func (r *Range) Validate() error {
 	if r.Min >= r.Max {
 		return fmt.Errorf("range min (%d) must be less than max (%d)", r.Min, r.Max)
 	}
-	_, err := r.CheckValue(*jsonx.NewNumber(float64(r.Default)))
+	if r.Step != "" {
+		step, err := r.step()
+		if err != nil {
+			return err
+		}
+		if step.Sign() <= 0 {
+			return fmt.Errorf("range step (%s) must be positive", r.Step)
+		}
+		if r.IntegerOnly && !step.IsInt() {
+			return fmt.Errorf("range step (%s) must be an integer when integer_only is set", r.Step)
+		}
+	}
+	_, err := r.CheckValue(*jsonx.NewNumber(r.Default))
 	return err
 }
 
-func (r *Range) SetLocale(locale string, provider locale.LocaleProvider) {
-	r.Label.SetLocale(locale, provider)
+// step parses the step as written, values must be Min plus a whole multiple of it
+func (r *Range) step() (*big.Rat, error) {
+	stepVal := jsonx.JSONValue{RawMessage: json.RawMessage(r.Step)}
+	step, err := stepVal.Decimal()
+	if err != nil {
+		return nil, fmt.Errorf("range step (%s) is not a number: %w", r.Step, err)
+	}
+	return step, nil
+}
+
+func (r *Range) Localize(locale string, provider locale.LocaleProvider) Element {
+	localized := *r
+	localized.Label = r.Label.Localize(locale, provider)
+	return &localized
 }
 
 func (r *Range) CheckValue(val jsonx.JSONValue) (jsonx.JSONValue, error) {
@@ -48,9 +81,25 @@ func (r *Range) CheckValue(val jsonx.JSONValue) (jsonx.JSONValue, error) {
 		return val, fmt.Errorf("value %v is not a number", val.Result().Value())
 	}
 
-	innerVal := val.Num()
-	if innerVal < float64(r.Min) || innerVal > float64(r.Max) {
-		return val, fmt.Errorf("value %v out of range [%d, %d]", innerVal, r.Min, r.Max)
+	innerVal, err := val.Decimal()
+	if err != nil {
+		return val, err
+	}
+	if innerVal.Cmp(big.NewRat(r.Min, 1)) < 0 || innerVal.Cmp(big.NewRat(r.Max, 1)) > 0 {
+		return val, fmt.Errorf("value %v out of range [%d, %d]", val.String(), r.Min, r.Max)
+	}
+	if r.IntegerOnly && !innerVal.IsInt() {
+		return val, fmt.Errorf("value %v is not an integer", val.String())
+	}
+	if r.Step != "" {
+		step, err := r.step()
+		if err != nil {
+			return val, err
+		}
+		offset := new(big.Rat).Sub(innerVal, big.NewRat(r.Min, 1))
+		if !offset.Quo(offset, step).IsInt() {
+			return val, fmt.Errorf("value %v is not a multiple of step %s from %d", val.String(), r.Step, r.Min)
+		}
 	}
 
 	return val, nil
@@ -63,7 +112,19 @@ func (r *Range) ToLiquid(val jsonx.JSONValue) (values.Value, error) {
 		return nil, err
 	}
 
-	innerVal := val.Num()
-	v := values.ValueOf(innerVal)
-	return v, nil
+	// integers are passed as int64 so big values keep their precision
+	if intVal, err := val.Int64(); err == nil {
+		return values.ValueOf(intVal), nil
+	}
+	return values.ValueOf(val.Num()), nil
+}
+
+func (r *Range) FormatValue(val jsonx.JSONValue, formatter *locale.Formatter) (string, error) {
+	var err error
+	val, err = r.CheckValue(val)
+	if err != nil {
+		return "", err
+	}
+
+	return formatter.FormatUnit(val.Num(), r.Unit), nil
 }
@@ -0,0 +1,61 @@
+package element
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+func TestRangeCheckValue(t *testing.T) {
+	r := &Range{ID: "opacity", Type: "range", Min: 0, Max: 1, Step: "0.1"}
+	intRange := &Range{ID: "count", Type: "range", Min: 1, Max: 9007199254740993, Step: "2", IntegerOnly: true}
+
+	tests := []struct {
+		name    string
+		r       *Range
+		raw     string
+		wantErr bool
+	}{
+		{name: "decimal step", r: r, raw: `0.3`},
+		{name: "decimal step upper bound", r: r, raw: `1`},
+		{name: "off step", r: r, raw: `0.25`, wantErr: true},
+		{name: "below min", r: r, raw: `-0.1`, wantErr: true},
+		{name: "big integer on step", r: intRange, raw: `9007199254740993`},
+		{name: "big integer off step", r: intRange, raw: `9007199254740992`, wantErr: true},
+		{name: "integer only", r: intRange, raw: `3.5`, wantErr: true},
+		{name: "integral fraction", r: intRange, raw: `3.0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.r.CheckValue(jsonx.JSONValue{RawMessage: json.RawMessage(tt.raw)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRangeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       *Range
+		wantErr bool
+	}{
+		{name: "valid", r: &Range{Min: 0, Max: 100, Default: 20, Step: "5", IntegerOnly: true}},
+		{name: "negative step", r: &Range{Min: 0, Max: 100, Step: "-1"}, wantErr: true},
+		{name: "fractional step for integers", r: &Range{Min: 0, Max: 100, Step: "0.5", IntegerOnly: true}, wantErr: true},
+		{name: "default off step", r: &Range{Min: 0, Max: 100, Default: 7, Step: "5"}, wantErr: true},
+		{name: "step exponent out of range", r: &Range{Min: 0, Max: 100, Step: "1e999999999"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package element
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+// Rule is a validation rule over several sibling element values, e.g.
+// "padding_top + padding_bottom <= 120". Expr uses the visible_if syntax and
+// has to hold, Message explains a violation to the merchant.
+type Rule struct {
+	ID      string                  `json:"id"`
+	Expr    string                  `json:"expr"`
+	Message field.TranslatableField `json:"message"`
+}
+
+// RuleViolation is the error of a rule that does not hold, Path names the
+// component or block, e.g. "component comp_title block blc_title"
+type RuleViolation struct {
+	Path    string
+	RuleID  string
+	Message field.TranslatableField
+}
+
+func (v *RuleViolation) Error() string {
+	return fmt.Sprintf("%s rule %s failed: %s", v.Path, v.RuleID, v.Message.String())
+}
+
+// Localize returns a copy of the violation with the message resolved for the
+// given locale, e.g. to show a violation of a shared schema to the merchant
+func (v *RuleViolation) Localize(locale string, localeProvider locale.LocaleProvider) *RuleViolation {
+	return &RuleViolation{
+		Path:    v.Path,
+		RuleID:  v.RuleID,
+		Message: v.Message.Localize(locale, localeProvider),
+	}
+}
+
+// Rules checks the validation rules of sibling elements
+type Rules struct {
+	rules      []Rule
+	conditions []*Condition
+	visibility *Visibility
+}
+
+// NewRules parses the rules of sibling elements, rule ids have to be unique
+// and expressions may only reference the elements of visibility
+func NewRules(rules []Rule, visibility *Visibility) (*Rules, error) {
+	r := &Rules{
+		rules:      rules,
+		conditions: make([]*Condition, 0, len(rules)),
+		visibility: visibility,
+	}
+	ruleIDs := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule id is empty")
+		}
+		if _, ok := ruleIDs[rule.ID]; ok {
+			return nil, fmt.Errorf("duplicated rule %s", rule.ID)
+		}
+		ruleIDs[rule.ID] = struct{}{}
+		if len(rule.Message.RawMessage) == 0 {
+			return nil, fmt.Errorf("rule %s has no message", rule.ID)
+		}
+		cond, err := ParseCondition(rule.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		for _, id := range cond.IDs() {
+			if _, ok := visibility.elements[id]; !ok {
+				return nil, fmt.Errorf("rule %s references unknown element %s", rule.ID, id)
+			}
+		}
+		r.conditions = append(r.conditions, cond)
+	}
+	return r, nil
+}
+
+// Localize returns a copy with the messages and the element defaults resolved
+// for the given locale, the parsed expressions are shared with the receiver
+func (r *Rules) Localize(locale string, localeProvider locale.LocaleProvider) *Rules {
+	if r == nil {
+		return nil
+	}
+	rules := make([]Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rule.Message = rule.Message.Localize(locale, localeProvider)
+		rules = append(rules, rule)
+	}
+	return &Rules{
+		rules:      rules,
+		conditions: r.conditions,
+		visibility: r.visibility.Localize(locale, localeProvider),
+	}
+}
+
+// Check evaluates the rules with settings, missing values fall back to the
+// element defaults. Rules referencing a hidden element or an element with
+// neither a value nor a default are skipped, every violation is returned as a
+// *RuleViolation joined into one error. A nil Rules has no rules.
+func (r *Rules) Check(path string, settings map[string]jsonx.JSONValue) error {
+	if r == nil {
+		return nil
+	}
+	lookup := r.visibility.lookup(settings)
+	violations := make([]error, 0)
+	for i, cond := range r.conditions {
+		skipped := false
+		for _, id := range cond.IDs() {
+			val, _ := lookup(id)
+			if len(val.RawMessage) == 0 || !r.visibility.IsVisible(id, settings) {
+				skipped = true
+				break
+			}
+		}
+		if skipped || cond.Eval(lookup) {
+			continue
+		}
+		violations = append(violations, &RuleViolation{
+			Path:    path,
+			RuleID:  r.rules[i].ID,
+			Message: r.rules[i].Message,
+		})
+	}
+	return errors.Join(violations...)
+}
@@ -0,0 +1,93 @@
+package element
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+func TestRulesCheck(t *testing.T) {
+	elements, err := UnmarshalElements([]jsonx.JSONValue{
+		{RawMessage: json.RawMessage(`{"type": "range", "id": "padding_top", "label": "Top", "min": 0, "max": 100, "default": 20}`)},
+		{RawMessage: json.RawMessage(`{"type": "range", "id": "padding_bottom", "label": "Bottom", "min": 0, "max": 100, "default": 20}`)},
+		{RawMessage: json.RawMessage(`{"type": "range", "id": "gap", "label": "Gap", "min": 0, "max": 100}`)},
+		{RawMessage: json.RawMessage(`{"type": "range", "id": "overlay", "label": "Overlay", "min": 0, "max": 100, "default": 0, "visible_if": "padding_top > 0"}`)},
+	})
+	if err != nil {
+		t.Fatalf("failed to unmarshal elements: %v", err)
+	}
+	visibility, err := NewVisibility(elements)
+	if err != nil {
+		t.Fatalf("failed to parse visibility: %v", err)
+	}
+	rules, err := NewRules([]Rule{
+		{ID: "padding_sum", Expr: "padding_top + padding_bottom <= 120", Message: field.TranslatableField{JSONValue: jsonx.JSONValue{RawMessage: json.RawMessage(`"t:rules.padding_sum"`)}}},
+		{ID: "gap", Expr: "gap <= padding_top", Message: field.TranslatableField{JSONValue: jsonx.JSONValue{RawMessage: json.RawMessage(`"Gap too large"`)}}},
+		{ID: "overlay", Expr: "overlay <= 50", Message: field.TranslatableField{JSONValue: jsonx.JSONValue{RawMessage: json.RawMessage(`"Overlay too strong"`)}}},
+	}, visibility)
+	if err != nil {
+		t.Fatalf("failed to parse rules: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		settings string
+		expected []string
+	}{
+		{name: "defaults hold", settings: `{}`},
+		{name: "default fills in", settings: `{"padding_top": 110}`, expected: []string{"padding_sum"}},
+		{name: "optional value missing", settings: `{"padding_top": 0}`},
+		{name: "optional value set", settings: `{"padding_top": 10, "gap": 20}`, expected: []string{"gap"}},
+		{name: "explicit null is evaluated", settings: `{"gap": null, "overlay": 80}`, expected: []string{"gap", "overlay"}},
+		{name: "hidden value", settings: `{"padding_top": 0, "overlay": 80}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings map[string]jsonx.JSONValue
+			if err := json.Unmarshal([]byte(tt.settings), &settings); err != nil {
+				t.Fatalf("failed to unmarshal settings: %v", err)
+			}
+			err := rules.Check("component comp", settings)
+			got := make([]string, 0)
+			if err != nil {
+				for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var violation *RuleViolation
+					if !errors.As(e, &violation) {
+						t.Fatalf("expected rule violation, got %v", e)
+					}
+					got = append(got, violation.RuleID)
+				}
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected violations %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected violations %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleViolationLocalize(t *testing.T) {
+	violation := &RuleViolation{
+		Path:    "component comp",
+		RuleID:  "padding_sum",
+		Message: field.TranslatableField{JSONValue: jsonx.JSONValue{RawMessage: json.RawMessage(`"t:rules.padding_sum"`)}},
+	}
+	provider := locale.NewJSONProvider([]byte(`{"rules": {"padding_sum": "Paddings are too large"}}`))
+
+	localized := violation.Localize("en-US", provider)
+	if got := localized.Error(); got != "component comp rule padding_sum failed: Paddings are too large" {
+		t.Fatalf("expected localized message, got %q", got)
+	}
+	if got := violation.Message.String(); got != "t:rules.padding_sum" {
+		t.Fatalf("expected violation to keep its translation key, got %q", got)
+	}
+}
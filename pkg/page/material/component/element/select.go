@@ -10,11 +10,12 @@ import (
 )
 
 type Select struct {
-	ID      string                  `json:"id"`
-	Type    string                  `json:"type"`
-	Default string                  `json:"default"`
-	Label   field.TranslatableField `json:"label"`
-	Options []SelectOption          `json:"options"`
+	ID        string                  `json:"id"`
+	Type      string                  `json:"type"`
+	Default   string                  `json:"default"`
+	Label     field.TranslatableField `json:"label"`
+	Options   []SelectOption          `json:"options"`
+	VisibleIf string                  `json:"visible_if,omitempty"`
 }
 
 type SelectOption struct {
@@ -26,6 +27,10 @@ func (s *Select) GetID() string {
 	return s.ID
 }
 
+func (s *Select) GetVisibleIf() string {
+	return s.VisibleIf
+}
+
 func (s *Select) EleType() ElementType {
 	return ElementTypeSelect
 }
@@ -62,11 +67,17 @@ func (s *Select) Validate() error {
 	return nil
 }
 
-func (s *Select) SetLocale(locale string, provider locale.LocaleProvider) {
-	s.Label.SetLocale(locale, provider)
-	for i := range s.Options {
-		s.Options[i].Label.SetLocale(locale, provider)
+func (s *Select) Localize(locale string, provider locale.LocaleProvider) Element {
+	localized := *s
+	localized.Label = s.Label.Localize(locale, provider)
+	localized.Options = make([]SelectOption, len(s.Options))
+	for i, option := range s.Options {
+		localized.Options[i] = SelectOption{
+			Value: option.Value,
+			Label: option.Label.Localize(locale, provider),
+		}
 	}
+	return &localized
 }
 
 func (s *Select) CheckValue(val jsonx.JSONValue) (jsonx.JSONValue, error) {
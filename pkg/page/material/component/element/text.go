@@ -16,12 +16,17 @@ type Text struct {
 	Label       field.TranslatableField  `json:"label"`
 	Placeholder *field.TranslatableField `json:"placeholder,omitempty"`
 	WordLimit   *int64                   `json:"word_limit,omitempty"`
+	VisibleIf   string                   `json:"visible_if,omitempty"`
 }
 
 func (t *Text) GetID() string {
 	return t.ID
 }
 
+func (t *Text) GetVisibleIf() string {
+	return t.VisibleIf
+}
+
 func (t *Text) EleType() ElementType {
 	return ElementTypeText
 }
@@ -37,12 +42,15 @@ func (t *Text) Validate() error {
 	return nil
 }
 
-func (t *Text) SetLocale(locale string, provider locale.LocaleProvider) {
+func (t *Text) Localize(locale string, provider locale.LocaleProvider) Element {
+	localized := *t
 	if t.Placeholder != nil {
-		t.Placeholder.SetLocale(locale, provider)
+		placeholder := t.Placeholder.Localize(locale, provider)
+		localized.Placeholder = &placeholder
 	}
-	t.Label.SetLocale(locale, provider)
-	t.Default.SetLocale(locale, provider)
+	localized.Label = t.Label.Localize(locale, provider)
+	localized.Default = t.Default.Localize(locale, provider)
+	return &localized
 }
 
 func (t *Text) CheckValue(val jsonx.JSONValue) (jsonx.JSONValue, error) {
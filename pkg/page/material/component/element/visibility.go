@@ -0,0 +1,134 @@
+package element
+
+import (
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+// Visibility evaluates the visible_if conditions of sibling elements, e.g.
+// the elements of a component or of a block
+type Visibility struct {
+	elements   map[string]Element
+	conditions map[string]*Condition
+}
+
+// NewVisibility parses the visible_if conditions of sibling elements, a
+// condition may only reference other siblings and references must not form
+// a cycle
+func NewVisibility(elements []Element) (*Visibility, error) {
+	v := &Visibility{
+		elements:   make(map[string]Element, len(elements)),
+		conditions: make(map[string]*Condition),
+	}
+	for _, ele := range elements {
+		v.elements[ele.GetID()] = ele
+	}
+	for _, ele := range elements {
+		if ele.GetVisibleIf() == "" {
+			continue
+		}
+		cond, err := ParseCondition(ele.GetVisibleIf())
+		if err != nil {
+			return nil, fmt.Errorf("element %s: %w", ele.GetID(), err)
+		}
+		for _, id := range cond.IDs() {
+			if _, ok := v.elements[id]; !ok {
+				return nil, fmt.Errorf("element %s visible_if references unknown element %s", ele.GetID(), id)
+			}
+		}
+		v.conditions[ele.GetID()] = cond
+	}
+
+	// visiting marks the elements on the current path, done the checked ones
+	visiting := make(map[string]bool, len(v.conditions))
+	var visit func(id string) error
+	visit = func(id string) error {
+		if done, ok := visiting[id]; ok {
+			if !done {
+				return fmt.Errorf("visible_if of element %s is cyclic", id)
+			}
+			return nil
+		}
+		visiting[id] = false
+		if cond, ok := v.conditions[id]; ok {
+			for _, ref := range cond.IDs() {
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		visiting[id] = true
+		return nil
+	}
+	for _, ele := range elements {
+		if err := visit(ele.GetID()); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Localize returns a copy with the element defaults resolved for the given
+// locale, the parsed conditions are shared with the receiver
+func (v *Visibility) Localize(locale string, localeProvider locale.LocaleProvider) *Visibility {
+	if v == nil {
+		return nil
+	}
+	elements := make(map[string]Element, len(v.elements))
+	for id, ele := range v.elements {
+		elements[id] = ele.Localize(locale, localeProvider)
+	}
+	return &Visibility{
+		elements:   elements,
+		conditions: v.conditions,
+	}
+}
+
+// Dependencies returns the elements the visible_if condition of element id
+// references, nil when it is always visible
+func (v *Visibility) Dependencies(id string) []string {
+	if v == nil {
+		return nil
+	}
+	cond, ok := v.conditions[id]
+	if !ok {
+		return nil
+	}
+	return cond.IDs()
+}
+
+// IsVisible reports whether element id is visible with settings. An element
+// is hidden when its condition is false or an element it references is
+// hidden, missing values fall back to the element default. A nil Visibility
+// has no conditions, every element is visible.
+func (v *Visibility) IsVisible(id string, settings map[string]jsonx.JSONValue) bool {
+	if v == nil {
+		return true
+	}
+	cond, ok := v.conditions[id]
+	if !ok {
+		return true
+	}
+	for _, ref := range cond.IDs() {
+		if !v.IsVisible(ref, settings) {
+			return false
+		}
+	}
+	return cond.Eval(v.lookup(settings))
+}
+
+// lookup returns the values of settings falling back to the element defaults
+func (v *Visibility) lookup(settings map[string]jsonx.JSONValue) func(id string) (jsonx.JSONValue, bool) {
+	return func(id string) (jsonx.JSONValue, bool) {
+		if val, ok := settings[id]; ok {
+			return val, true
+		}
+		ele, ok := v.elements[id]
+		if !ok {
+			return jsonx.JSONValue{}, false
+		}
+		return ele.GetDefault(), true
+	}
+}
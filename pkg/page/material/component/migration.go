@@ -0,0 +1,309 @@
+package component
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
+)
+
+type MigrationOp string
+
+const (
+	// MigrationRenameElement moves the value of Element to To, a value already
+	// at To is overwritten and reported in the change
+	MigrationRenameElement MigrationOp = "rename_element"
+	// MigrationMapSelectValues replaces the string values of Element found in Values
+	MigrationMapSelectValues MigrationOp = "map_select_values"
+	// MigrationClampRange clamps the number value of Element into [Min, Max]
+	MigrationClampRange MigrationOp = "clamp_range"
+	// MigrationSplitBlockType changes the type of BlockType blocks to the type
+	// Cases maps the value of Element to, or to To when no case matches
+	MigrationSplitBlockType MigrationOp = "split_block_type"
+)
+
+// Migration upgrades settings to Version of the component schema, steps run in order
+type Migration struct {
+	Version uint32          `json:"version"`
+	Steps   []MigrationStep `json:"steps"`
+}
+
+// MigrationStep is a single declarative change, element steps apply to the
+// component settings or, with BlockType, to every block of that type
+type MigrationStep struct {
+	Op        MigrationOp       `json:"op"`
+	BlockType string            `json:"block_type,omitempty"`
+	Element   string            `json:"element,omitempty"`
+	To        string            `json:"to,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+	Min       *int64            `json:"min,omitempty"`
+	Max       *int64            `json:"max,omitempty"`
+	Cases     map[string]string `json:"cases,omitempty"`
+}
+
+// MigrationChange reports a change made by Migrate, Scope names the changed
+// component or block, e.g. "component comp_title block blc_title"
+type MigrationChange struct {
+	Version uint32
+	Op      MigrationOp
+	Scope   string
+	Detail  string
+}
+
+func (c MigrationChange) String() string {
+	return fmt.Sprintf("v%d %s: %s", c.Version, c.Scope, c.Detail)
+}
+
+// Migrate runs the migrations newer than the schema version of settings and
+// returns the migrated settings at the current version, settings is left untouched
+func (s *Schema) Migrate(compID string, settings Settings) (Settings, []MigrationChange, error) {
+	if settings.SchemaVersion > s.Version {
+		return settings, nil, fmt.Errorf(
+			"component %s is at schema version %d, newer than %d", compID, settings.SchemaVersion, s.Version,
+		)
+	}
+	if settings.SchemaVersion == s.Version {
+		return settings, nil, nil
+	}
+
+	scope := fmt.Sprintf("component %s", compID)
+	if settings.ElementSettings != nil {
+		settings.ElementSettings = cloneElementSettings(settings.ElementSettings)
+	}
+	settings.Blocks = cloneBlockSettings(settings.Blocks)
+
+	changes := make([]MigrationChange, 0)
+	for _, migration := range s.Migrations {
+		if migration.Version <= settings.SchemaVersion {
+			continue
+		}
+		from := len(changes)
+		for _, step := range migration.Steps {
+			if step.BlockType != "" {
+				changes = step.applyBlocks(scope, settings.Blocks, changes)
+				continue
+			}
+			if detail, ok := step.applyElement(settings.ElementSettings); ok {
+				changes = append(changes, MigrationChange{Op: step.Op, Scope: scope, Detail: detail})
+			}
+		}
+		for i := from; i < len(changes); i++ {
+			changes[i].Version = migration.Version
+		}
+	}
+	settings.SchemaVersion = s.Version
+	return settings, changes, nil
+}
+
+// applyBlocks applies the step to the blocks of BlockType in blockSettings and
+// their children, the changes are appended without version
+func (step MigrationStep) applyBlocks(scope string, blockSettings map[string]blocks.Settings, changes []MigrationChange) []MigrationChange {
+	blockIDs := make([]string, 0, len(blockSettings))
+	for blockID := range blockSettings {
+		blockIDs = append(blockIDs, blockID)
+	}
+	slices.Sort(blockIDs)
+
+	for _, blockID := range blockIDs {
+		block := blockSettings[blockID]
+		blockScope := fmt.Sprintf("%s block %s", scope, blockID)
+		if block.Type == step.BlockType {
+			var detail string
+			var ok bool
+			if step.Op == MigrationSplitBlockType {
+				detail, ok = step.splitBlock(&block)
+			} else {
+				detail, ok = step.applyElement(block.ElementSettings)
+			}
+			if ok {
+				changes = append(changes, MigrationChange{Op: step.Op, Scope: blockScope, Detail: detail})
+			}
+		}
+		changes = step.applyBlocks(blockScope, block.Blocks, changes)
+		blockSettings[blockID] = block
+	}
+	return changes
+}
+
+// applyElement applies an element step to settings in place
+func (step MigrationStep) applyElement(settings map[string]jsonx.JSONValue) (string, bool) {
+	val, ok := settings[step.Element]
+	if !ok {
+		return "", false
+	}
+	switch step.Op {
+	case MigrationRenameElement:
+		delete(settings, step.Element)
+		prev, overwritten := settings[step.To]
+		settings[step.To] = val
+		if overwritten {
+			return fmt.Sprintf("renamed element %s to %s, overwriting value %s", step.Element, step.To, prev.String()), true
+		}
+		return fmt.Sprintf("renamed element %s to %s", step.Element, step.To), true
+	case MigrationMapSelectValues:
+		if !val.IsString() {
+			return "", false
+		}
+		to, ok := step.Values[val.String()]
+		if !ok {
+			return "", false
+		}
+		mapped, err := jsonx.NewString(to)
+		if err != nil {
+			return "", false
+		}
+		settings[step.Element] = *mapped
+		return fmt.Sprintf("mapped element %s value %q to %q", step.Element, val.String(), to), true
+	case MigrationClampRange:
+		num, err := val.Decimal()
+		if err != nil {
+			return "", false
+		}
+		var bound *int64
+		if step.Min != nil && num.Cmp(big.NewRat(*step.Min, 1)) < 0 {
+			bound = step.Min
+		} else if step.Max != nil && num.Cmp(big.NewRat(*step.Max, 1)) > 0 {
+			bound = step.Max
+		}
+		if bound == nil {
+			return "", false
+		}
+		settings[step.Element] = *jsonx.NewNumber(*bound)
+		return fmt.Sprintf("clamped element %s value %s to %d", step.Element, val.String(), *bound), true
+	}
+	return "", false
+}
+
+func (step MigrationStep) splitBlock(block *blocks.Settings) (string, bool) {
+	blockType := step.To
+	if val, ok := block.ElementSettings[step.Element]; ok {
+		if caseType, ok := step.Cases[val.String()]; ok {
+			blockType = caseType
+		}
+	}
+	if blockType == "" || blockType == block.Type {
+		return "", false
+	}
+	detail := fmt.Sprintf("split block type %s to %s", block.Type, blockType)
+	block.Type = blockType
+	return detail, true
+}
+
+func cloneBlockSettings(blockSettings map[string]blocks.Settings) map[string]blocks.Settings {
+	if blockSettings == nil {
+		return nil
+	}
+	cloned := make(map[string]blocks.Settings, len(blockSettings))
+	for blockID, block := range blockSettings {
+		if block.ElementSettings != nil {
+			block.ElementSettings = cloneElementSettings(block.ElementSettings)
+		}
+		block.BlockOrder = slices.Clone(block.BlockOrder)
+		block.Blocks = cloneBlockSettings(block.Blocks)
+		cloned[blockID] = block
+	}
+	return cloned
+}
+
+// parseMigrations converts the raw migrations, versions have to increase and
+// must not exceed the schema version. Blocks are split into types declared in
+// blockSchemas at any depth, the split type itself may be gone already.
+func parseMigrations(
+	version uint32,
+	rawMigrations []jsonmodel.ComponentMigration,
+	blockSchemas []blocks.Schema,
+) ([]Migration, error) {
+	blockTypes := make(map[string]struct{})
+	collectBlockTypes(blockSchemas, blockTypes)
+
+	migrations := make([]Migration, 0, len(rawMigrations))
+	prevVersion := uint32(0)
+	for _, rawMigration := range rawMigrations {
+		if rawMigration.Version <= prevVersion {
+			return nil, fmt.Errorf("migration version %d must be greater than %d", rawMigration.Version, prevVersion)
+		}
+		if rawMigration.Version > version {
+			return nil, fmt.Errorf("migration version %d exceeds schema version %d", rawMigration.Version, version)
+		}
+		prevVersion = rawMigration.Version
+
+		steps := make([]MigrationStep, 0, len(rawMigration.Steps))
+		for i, rawStep := range rawMigration.Steps {
+			step := MigrationStep{
+				Op:        MigrationOp(rawStep.Op),
+				BlockType: rawStep.BlockType,
+				Element:   rawStep.Element,
+				To:        rawStep.To,
+				Values:    rawStep.Values,
+				Min:       rawStep.Min,
+				Max:       rawStep.Max,
+				Cases:     rawStep.Cases,
+			}
+			if err := step.validate(blockTypes); err != nil {
+				return nil, fmt.Errorf("migration %d step %d: %w", rawMigration.Version, i, err)
+			}
+			steps = append(steps, step)
+		}
+		migrations = append(migrations, Migration{
+			Version: rawMigration.Version,
+			Steps:   steps,
+		})
+	}
+	return migrations, nil
+}
+
+func collectBlockTypes(blockSchemas []blocks.Schema, blockTypes map[string]struct{}) {
+	for _, blockSchema := range blockSchemas {
+		blockTypes[blockSchema.Type] = struct{}{}
+		collectBlockTypes(blockSchema.Blocks, blockTypes)
+	}
+}
+
+func (step MigrationStep) validate(blockTypes map[string]struct{}) error {
+	if step.Element == "" {
+		return fmt.Errorf("%s requires an element", step.Op)
+	}
+	switch step.Op {
+	case MigrationRenameElement:
+		if step.To == "" || step.To == step.Element {
+			return fmt.Errorf("%s requires a new element id", step.Op)
+		}
+	case MigrationMapSelectValues:
+		if len(step.Values) == 0 {
+			return fmt.Errorf("%s requires values", step.Op)
+		}
+	case MigrationClampRange:
+		if step.Min == nil && step.Max == nil {
+			return fmt.Errorf("%s requires min or max", step.Op)
+		}
+		if step.Min != nil && step.Max != nil && *step.Min > *step.Max {
+			return fmt.Errorf("%s min (%d) must not exceed max (%d)", step.Op, *step.Min, *step.Max)
+		}
+	case MigrationSplitBlockType:
+		if step.BlockType == "" {
+			return fmt.Errorf("%s requires a block type", step.Op)
+		}
+		if len(step.Cases) == 0 && step.To == "" {
+			return fmt.Errorf("%s requires cases or a default type", step.Op)
+		}
+		targets := make([]string, 0, len(step.Cases)+1)
+		if step.To != "" {
+			targets = append(targets, step.To)
+		}
+		for _, caseType := range step.Cases {
+			targets = append(targets, caseType)
+		}
+		slices.Sort(targets)
+		for _, target := range targets {
+			if _, ok := blockTypes[target]; !ok {
+				return fmt.Errorf("%s target block type %s is not declared", step.Op, target)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown migration op %q", step.Op)
+	}
+	return nil
+}
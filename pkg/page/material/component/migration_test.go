@@ -0,0 +1,259 @@
+package component
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
+)
+
+var migratedSchemaRaw = []byte(`{
+	"name": "banner",
+	"version": 2,
+	"elements": [
+		{"type": "select", "id": "style", "label": "Style", "default": "b", "options": [
+			{"value": "a", "label": "A"}, {"value": "b", "label": "B"}
+		]},
+		{"type": "range", "id": "padding", "label": "Padding", "min": 0, "max": 100, "default": 10}
+	],
+	"blocks": [
+		{"type": "heading", "name": "Heading", "elements": [{"type": "text", "id": "level", "label": "Level"}]},
+		{"type": "title", "name": "Title", "elements": []},
+		{"type": "sub_title", "name": "Sub title", "elements": []}
+	],
+	"presets": [{"id": "default", "name": "Default", "element_settings": {"style": "a"}}],
+	"migrations": [
+		{"version": 1, "steps": [
+			{"op": "rename_element", "element": "padding_old", "to": "padding"},
+			{"op": "map_select_values", "element": "style", "values": {"a": "b"}}
+		]},
+		{"version": 2, "steps": [
+			{"op": "clamp_range", "element": "padding", "min": 0, "max": 100},
+			{"op": "split_block_type", "block_type": "heading", "element": "level", "cases": {"2": "sub_title"}, "to": "title"}
+		]}
+	]
+}`)
+
+func parseMigratedSchema(t *testing.T) *Schema {
+	t.Helper()
+	var raw jsonmodel.ComponentSchema
+	if err := json.Unmarshal(migratedSchemaRaw, &raw); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	schema, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	return schema
+}
+
+func TestMigrate(t *testing.T) {
+	schema := parseMigratedSchema(t)
+
+	tests := []struct {
+		name            string
+		version         uint32
+		expectedChanges []string
+		expectedStyle   string
+		expectedPadding string
+		expectedTypes   string
+		wantErr         bool
+	}{
+		{
+			name:    "from scratch",
+			version: 0,
+			expectedChanges: []string{
+				`v1 component comp: renamed element padding_old to padding`,
+				`v1 component comp: mapped element style value "a" to "b"`,
+				`v2 component comp: clamped element padding value 150 to 100`,
+				`v2 component comp block blc_a: split block type heading to sub_title`,
+				`v2 component comp block blc_b: split block type heading to title`,
+			},
+			expectedStyle:   "b",
+			expectedPadding: "100",
+			expectedTypes:   "sub_title,title",
+		},
+		{
+			name:    "from v1",
+			version: 1,
+			expectedChanges: []string{
+				`v2 component comp block blc_a: split block type heading to sub_title`,
+				`v2 component comp block blc_b: split block type heading to title`,
+			},
+			expectedStyle: "a",
+			expectedTypes: "sub_title,title",
+		},
+		{name: "current", version: 2, expectedStyle: "a", expectedTypes: "heading,heading"},
+		{name: "newer", version: 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := Settings{
+				ID:            "comp",
+				SchemaVersion: tt.version,
+				BlockOrder:    []string{"blc_a", "blc_b"},
+				Blocks: map[string]blocks.Settings{
+					"blc_a": {ID: "blc_a", Type: "heading", ElementSettings: map[string]jsonx.JSONValue{"level": *mustString(t, "2")}},
+					"blc_b": {ID: "blc_b", Type: "heading", ElementSettings: map[string]jsonx.JSONValue{"level": *mustString(t, "1")}},
+				},
+				ElementSettings: map[string]jsonx.JSONValue{
+					"style":       *mustString(t, "a"),
+					"padding_old": *jsonx.NewNumber(int64(150)),
+				},
+			}
+
+			migrated, changes, err := schema.Migrate("comp", settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make([]string, 0, len(changes))
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.expectedChanges, "\n") {
+				t.Fatalf("expected changes %v, got %v", tt.expectedChanges, got)
+			}
+			if migrated.SchemaVersion != schema.Version {
+				t.Fatalf("expected schema version %d, got %d", schema.Version, migrated.SchemaVersion)
+			}
+			if got := migrated.ElementSettings["style"].String(); got != tt.expectedStyle {
+				t.Fatalf("expected style %q, got %q", tt.expectedStyle, got)
+			}
+			if got := migrated.ElementSettings["padding"].String(); got != tt.expectedPadding {
+				t.Fatalf("expected padding %q, got %q", tt.expectedPadding, got)
+			}
+			types := migrated.Blocks["blc_a"].Type + "," + migrated.Blocks["blc_b"].Type
+			if types != tt.expectedTypes {
+				t.Fatalf("expected block types %s, got %s", tt.expectedTypes, types)
+			}
+
+			// the input settings are left untouched
+			if settings.ElementSettings["style"].String() != "a" || settings.Blocks["blc_a"].Type != "heading" {
+				t.Fatal("expected Migrate to leave the input settings untouched")
+			}
+		})
+	}
+}
+
+func TestMigrateRenameOverwrite(t *testing.T) {
+	schema := parseMigratedSchema(t)
+	settings := Settings{
+		ID: "comp",
+		ElementSettings: map[string]jsonx.JSONValue{
+			"padding_old": *jsonx.NewNumber(int64(20)),
+			"padding":     *jsonx.NewNumber(int64(30)),
+		},
+	}
+
+	migrated, changes, err := schema.Migrate("comp", settings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "v1 component comp: renamed element padding_old to padding, overwriting value 30"
+	if len(changes) == 0 || changes[0].String() != expected {
+		t.Fatalf("expected change %q, got %v", expected, changes)
+	}
+	if got := migrated.ElementSettings["padding"].String(); got != "20" {
+		t.Fatalf("expected padding 20, got %s", got)
+	}
+}
+
+func TestPresetInstantiateIsCurrent(t *testing.T) {
+	schema := parseMigratedSchema(t)
+	preset, ok := schema.GetPreset("default")
+	if !ok {
+		t.Fatal("expected preset default")
+	}
+	settings, err := preset.Instantiate("comp", "banner")
+	if err != nil {
+		t.Fatalf("failed to instantiate preset: %v", err)
+	}
+	if settings.SchemaVersion != schema.Version {
+		t.Fatalf("expected schema version %d, got %d", schema.Version, settings.SchemaVersion)
+	}
+
+	migrated, changes, err := schema.Migrate(settings.ID, settings)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(changes) != 0 || migrated.ElementSettings["style"].String() != "a" {
+		t.Fatalf("expected preset values not to be migrated, got %v", changes)
+	}
+}
+
+func TestParseMigrationsInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    uint32
+		migrations string
+		expected   string
+	}{
+		{
+			name:       "newer than the schema",
+			version:    1,
+			migrations: `[{"version": 2, "steps": []}]`,
+			expected:   "migration version 2 exceeds schema version 1",
+		},
+		{
+			name:       "version not increasing",
+			version:    2,
+			migrations: `[{"version": 2, "steps": []}, {"version": 1, "steps": []}]`,
+			expected:   "migration version 1 must be greater than 2",
+		},
+		{
+			name:       "unknown op",
+			version:    1,
+			migrations: `[{"version": 1, "steps": [{"op": "drop_element", "element": "padding"}]}]`,
+			expected:   `unknown migration op "drop_element"`,
+		},
+		{
+			name:       "undeclared split target",
+			version:    1,
+			migrations: `[{"version": 1, "steps": [{"op": "split_block_type", "block_type": "heading", "element": "level", "to": "banner"}]}]`,
+			expected:   "split_block_type target block type banner is not declared",
+		},
+		{
+			name:       "undeclared split case",
+			version:    1,
+			migrations: `[{"version": 1, "steps": [{"op": "split_block_type", "block_type": "heading", "element": "level", "cases": {"1": "title", "2": "caption"}}]}]`,
+			expected:   "split_block_type target block type caption is not declared",
+		},
+		{
+			name:       "rename to itself",
+			version:    1,
+			migrations: `[{"version": 1, "steps": [{"op": "rename_element", "element": "padding", "to": "padding"}]}]`,
+			expected:   "rename_element requires a new element id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw []jsonmodel.ComponentMigration
+			if err := json.Unmarshal([]byte(tt.migrations), &raw); err != nil {
+				t.Fatalf("failed to unmarshal migrations: %v", err)
+			}
+			// nested block types are valid targets
+			blockSchemas := []blocks.Schema{{Type: "group", Blocks: []blocks.Schema{{Type: "title"}}}}
+			_, err := parseMigrations(tt.version, raw, blockSchemas)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func mustString(t *testing.T, s string) *jsonx.JSONValue {
+	t.Helper()
+	val, err := jsonx.NewString(s)
+	if err != nil {
+		t.Fatalf("failed to encode %q: %v", s, err)
+	}
+	return val
+}
@@ -0,0 +1,216 @@
+package component
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+)
+
+// Preset is a starter configuration of a component, e.g. a product title
+// with a title and a sub title block, the editor inserts it in one click
+type Preset struct {
+	ID              string                     `json:"id"`
+	Name            field.TranslatableField    `json:"name"`
+	ElementSettings map[string]jsonx.JSONValue `json:"element_settings,omitempty"`
+	Blocks          []PresetBlock              `json:"blocks,omitempty"`
+
+	// schemaVersion is the version of the schema the preset was parsed with
+	schemaVersion uint32
+}
+
+type PresetBlock struct {
+	Type            string                     `json:"type"`
+	ElementSettings map[string]jsonx.JSONValue `json:"element_settings,omitempty"`
+	Blocks          []PresetBlock              `json:"blocks,omitempty"`
+}
+
+// GetPreset returns the preset with id
+func (s *Schema) GetPreset(id string) (*Preset, bool) {
+	for i := range s.Presets {
+		if s.Presets[i].ID == id {
+			return &s.Presets[i], true
+		}
+	}
+	return nil, false
+}
+
+// Instantiate creates the settings of a new component named compName from
+// the preset, every block gets a freshly generated id. The settings are at
+// the version of the schema so migrations never run over preset values.
+func (p *Preset) Instantiate(compID, compName string) (Settings, error) {
+	blockOrder, blockSettings, err := instantiateBlocks(p.Blocks)
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{
+		ID:              compID,
+		Name:            compName,
+		SchemaVersion:   p.schemaVersion,
+		BlockOrder:      blockOrder,
+		Blocks:          blockSettings,
+		ElementSettings: cloneElementSettings(p.ElementSettings),
+	}, nil
+}
+
+func instantiateBlocks(presetBlocks []PresetBlock) ([]string, map[string]blocks.Settings, error) {
+	if len(presetBlocks) == 0 {
+		return nil, nil, nil
+	}
+	blockOrder := make([]string, 0, len(presetBlocks))
+	blockSettings := make(map[string]blocks.Settings, len(presetBlocks))
+	for _, presetBlock := range presetBlocks {
+		blockID, err := newBlockID()
+		if err != nil {
+			return nil, nil, err
+		}
+		childOrder, childSettings, err := instantiateBlocks(presetBlock.Blocks)
+		if err != nil {
+			return nil, nil, err
+		}
+		blockOrder = append(blockOrder, blockID)
+		blockSettings[blockID] = blocks.Settings{
+			Type:            presetBlock.Type,
+			ID:              blockID,
+			ElementSettings: cloneElementSettings(presetBlock.ElementSettings),
+			BlockOrder:      childOrder,
+			Blocks:          childSettings,
+		}
+	}
+	return blockOrder, blockSettings, nil
+}
+
+func newBlockID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate block id: %w", err)
+	}
+	return "blc_" + hex.EncodeToString(b), nil
+}
+
+func cloneElementSettings(settings map[string]jsonx.JSONValue) map[string]jsonx.JSONValue {
+	cloned := make(map[string]jsonx.JSONValue, len(settings))
+	for id, val := range settings {
+		cloned[id] = val
+	}
+	return cloned
+}
+
+// parsePresets converts the raw presets and validates them against the schema,
+// element values and rules are checked like Validate checks stored settings
+func parsePresets(
+	version uint32,
+	rawPresets []jsonmodel.ComponentPreset,
+	elements []element.Element,
+	visibility *element.Visibility,
+	ruleChecker *element.Rules,
+	maxBlocks *uint8,
+	blockSchemas []blocks.Schema,
+) ([]Preset, error) {
+	presets := make([]Preset, 0, len(rawPresets))
+	presetIDs := make(map[string]struct{}, len(rawPresets))
+	for _, rawPreset := range rawPresets {
+		if _, ok := presetIDs[rawPreset.ID]; ok {
+			return nil, fmt.Errorf("duplicated preset %s", rawPreset.ID)
+		}
+		presetIDs[rawPreset.ID] = struct{}{}
+
+		scope := fmt.Sprintf("preset %s", rawPreset.ID)
+		err := checkPresetElementSettings(scope, rawPreset.ElementSettings, elements, visibility, ruleChecker)
+		if err != nil {
+			return nil, err
+		}
+		presetBlocks, err := parsePresetBlocks(scope, rawPreset.Blocks, maxBlocks, blockSchemas)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, Preset{
+			ID:              rawPreset.ID,
+			Name:            rawPreset.Name,
+			ElementSettings: rawPreset.ElementSettings,
+			Blocks:          presetBlocks,
+			schemaVersion:   version,
+		})
+	}
+	return presets, nil
+}
+
+func parsePresetBlocks(
+	scope string,
+	rawBlocks []jsonmodel.PresetBlock,
+	maxBlocks *uint8,
+	blockSchemas []blocks.Schema,
+) ([]PresetBlock, error) {
+	if maxBlocks != nil && len(rawBlocks) > int(*maxBlocks) {
+		return nil, fmt.Errorf("%s %d blocks exceed max block limit of %d", scope, len(rawBlocks), *maxBlocks)
+	}
+	blockSchemaMap := make(map[string]*blocks.Schema, len(blockSchemas))
+	for _, blockSchema := range blockSchemas {
+		blockSchemaMap[blockSchema.Type] = &blockSchema
+	}
+
+	presetBlocks := make([]PresetBlock, 0, len(rawBlocks))
+	blockTypeCounter := make(map[string]uint8, len(blockSchemas))
+	for _, rawBlock := range rawBlocks {
+		blockSchema, ok := blockSchemaMap[rawBlock.Type]
+		if !ok {
+			return nil, fmt.Errorf("%s schema for block type %s not found", scope, rawBlock.Type)
+		}
+		blockTypeCounter[rawBlock.Type]++
+		if blockSchema.Limit != nil && blockTypeCounter[rawBlock.Type] > *blockSchema.Limit {
+			return nil, fmt.Errorf("%s block type %s exceeds limit of %d", scope, rawBlock.Type, *blockSchema.Limit)
+		}
+		blockScope := fmt.Sprintf("%s block %s", scope, rawBlock.Type)
+		err := checkPresetElementSettings(
+			blockScope, rawBlock.ElementSettings,
+			blockSchema.Elements, blockSchema.Visibility(), blockSchema.RuleChecker(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		children, err := parsePresetBlocks(blockScope, rawBlock.Blocks, blockSchema.MaxBlocks, blockSchema.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		presetBlocks = append(presetBlocks, PresetBlock{
+			Type:            rawBlock.Type,
+			ElementSettings: rawBlock.ElementSettings,
+			Blocks:          children,
+		})
+	}
+	return presetBlocks, nil
+}
+
+// checkPresetElementSettings requires every value to belong to a declared
+// element and pass its check, and the values to pass the rules. Values of
+// elements hidden by visible_if are kept unchecked.
+func checkPresetElementSettings(
+	scope string,
+	settings map[string]jsonx.JSONValue,
+	elements []element.Element,
+	visibility *element.Visibility,
+	ruleChecker *element.Rules,
+) error {
+	elementMap := make(map[string]element.Element, len(elements))
+	for _, ele := range elements {
+		elementMap[ele.GetID()] = ele
+	}
+	for id, val := range settings {
+		ele, ok := elementMap[id]
+		if !ok {
+			return fmt.Errorf("%s element %s is not declared", scope, id)
+		}
+		if !visibility.IsVisible(id, settings) {
+			continue
+		}
+		if _, err := ele.CheckValue(val); err != nil {
+			return fmt.Errorf("%s element %s value check failed: %w", scope, id, err)
+		}
+	}
+	return ruleChecker.Check(scope, settings)
+}
@@ -0,0 +1,175 @@
+package component
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+var titleSchemaRaw = []byte(`{
+	"name": "title",
+	"max_blocks": 2,
+	"elements": [
+		{"type": "range", "id": "padding_top", "label": "Top", "min": 0, "max": 100, "default": 36}
+	],
+	"blocks": [
+		{"type": "title", "name": "Title", "limit": 1, "elements": [
+			{"type": "select", "id": "title_size", "label": "Size", "default": "h1", "options": [
+				{"value": "h1", "label": "H1"}, {"value": "h2", "label": "H2"}
+			]}
+		]},
+		{"type": "sub_title", "name": "Sub title", "limit": 1, "elements": []}
+	],
+	"presets": [{
+		"id": "default",
+		"name": "t:presets.default",
+		"element_settings": {"padding_top": 24},
+		"blocks": [{"type": "title", "element_settings": {"title_size": "h2"}}, {"type": "sub_title"}]
+	}]
+}`)
+
+// parseTitleSchema parses titleSchemaRaw with the presets replaced by presets
+// unless it is empty
+func parseTitleSchema(presets string) (*Schema, error) {
+	raw := jsonx.JSONValue{RawMessage: append([]byte(nil), titleSchemaRaw...)}
+	if presets != "" {
+		if err := raw.Set("presets", json.RawMessage(presets)); err != nil {
+			return nil, err
+		}
+	}
+	var rawSchema jsonmodel.ComponentSchema
+	if err := json.Unmarshal(raw.RawMessage, &rawSchema); err != nil {
+		return nil, err
+	}
+	return Parse(rawSchema)
+}
+
+func TestPresetInstantiate(t *testing.T) {
+	schema, err := parseTitleSchema("")
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	localized := schema.Localize("zh-CN", locale.NewJSONProvider([]byte(`{"presets": {"default": "标题与副标题"}}`)))
+	if name := localized.Presets[0].Name.String(); name != "标题与副标题" {
+		t.Fatalf("expected localized preset name, got %s", name)
+	}
+
+	preset, ok := schema.GetPreset("default")
+	if !ok {
+		t.Fatal("expected preset default")
+	}
+	first, err := preset.Instantiate("comp_title_1", "title")
+	if err != nil {
+		t.Fatalf("failed to instantiate preset: %v", err)
+	}
+	second, err := preset.Instantiate("comp_title_2", "title")
+	if err != nil {
+		t.Fatalf("failed to instantiate preset: %v", err)
+	}
+	if len(first.BlockOrder) != 2 || first.Blocks[first.BlockOrder[1]].Type != "sub_title" {
+		t.Fatalf("expected title and sub title blocks, got %v", first.BlockOrder)
+	}
+	if first.BlockOrder[0] == second.BlockOrder[0] {
+		t.Fatalf("expected fresh block ids for every instance, got %s twice", first.BlockOrder[0])
+	}
+	if first.Blocks[first.BlockOrder[0]].ID != first.BlockOrder[0] {
+		t.Fatal("expected block id to match its key")
+	}
+
+	// instances do not share their settings with the preset
+	first.ElementSettings["padding_top"] = *jsonx.NewNumber(int64(1))
+	if got := second.ElementSettings["padding_top"].String(); got != "24" {
+		t.Fatalf("expected preset settings to be copied, got %s", got)
+	}
+}
+
+func TestParsePresetsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		presets string
+	}{
+		{name: "duplicated id", presets: `[{"id": "p", "name": "p"}, {"id": "p", "name": "p"}]`},
+		{name: "unknown block type", presets: `[{"id": "p", "name": "p", "blocks": [{"type": "missing"}]}]`},
+		{name: "block type limit", presets: `[{"id": "p", "name": "p", "blocks": [{"type": "title"}, {"type": "title"}]}]`},
+		{name: "max blocks", presets: `[{"id": "p", "name": "p", "blocks": [{"type": "title"}, {"type": "sub_title"}, {"type": "sub_title"}]}]`},
+		{name: "undeclared element", presets: `[{"id": "p", "name": "p", "element_settings": {"margin": 1}}]`},
+		{name: "invalid value", presets: `[{"id": "p", "name": "p", "element_settings": {"padding_top": 1000}}]`},
+		{name: "invalid block value", presets: `[{"id": "p", "name": "p", "blocks": [{"type": "title", "element_settings": {"title_size": "h9"}}]}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseTitleSchema(tt.presets); err == nil {
+				t.Fatal("expected invalid preset to fail parsing")
+			}
+		})
+	}
+}
+
+var ruledSchemaRaw = []byte(`{
+	"name": "title",
+	"elements": [
+		{"type": "range", "id": "padding_top", "label": "Top", "min": 0, "max": 100, "default": 36},
+		{"type": "range", "id": "padding_bottom", "label": "Bottom", "min": 0, "max": 100, "default": 36, "visible_if": "padding_top >= 10"}
+	],
+	"rules": [{"id": "padding_sum", "expr": "padding_top + padding_bottom <= 120", "message": "m"}],
+	"blocks": [{"type": "sub_title", "name": "Sub title", "elements": [
+		{"type": "text", "id": "sub_title_text", "label": "Text", "default": ""},
+		{"type": "range", "id": "sub_title_opacity", "label": "Opacity", "min": 0, "max": 100, "default": 100, "visible_if": "sub_title_text != ''"}
+	], "rules": [{"id": "min_opacity", "expr": "sub_title_opacity >= 10", "message": "m"}]}]
+}`)
+
+func TestParsePresetsRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		expected string
+	}{
+		{name: "valid", preset: `{"id": "p", "name": "p", "element_settings": {"padding_top": 50, "padding_bottom": 50}}`},
+		{name: "hidden element unchecked", preset: `{"id": "p", "name": "p", "element_settings": {"padding_top": 0, "padding_bottom": 1000}}`},
+		{
+			name:     "component rule",
+			preset:   `{"id": "p", "name": "p", "element_settings": {"padding_top": 100, "padding_bottom": 100}}`,
+			expected: "preset p",
+		},
+		{
+			name:     "block rule",
+			preset:   `{"id": "p", "name": "p", "blocks": [{"type": "sub_title", "element_settings": {"sub_title_text": "x", "sub_title_opacity": 5}}]}`,
+			expected: "preset p block sub_title",
+		},
+		{name: "hidden block element", preset: `{"id": "p", "name": "p", "blocks": [{"type": "sub_title", "element_settings": {"sub_title_opacity": 5}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := jsonx.JSONValue{RawMessage: append([]byte(nil), ruledSchemaRaw...)}
+			if err := raw.Set("presets", json.RawMessage("["+tt.preset+"]")); err != nil {
+				t.Fatalf("failed to set presets: %v", err)
+			}
+			var rawSchema jsonmodel.ComponentSchema
+			if err := json.Unmarshal(raw.RawMessage, &rawSchema); err != nil {
+				t.Fatalf("failed to unmarshal schema: %v", err)
+			}
+
+			_, err := Parse(rawSchema)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var violation *element.RuleViolation
+			if !errors.As(err, &violation) {
+				t.Fatalf("expected a rule violation, got %v", err)
+			}
+			if violation.Path != tt.expected {
+				t.Fatalf("expected violation at %s, got %s", tt.expected, violation.Path)
+			}
+		})
+	}
+}
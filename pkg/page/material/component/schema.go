@@ -6,48 +6,132 @@ import (
 	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
 	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
 type Schema struct {
-	Name      string            `json:"name"`
-	MaxBlocks *uint8            `json:"max_blocks,omitempty"`
-	Blocks    []blocks.Schema   `json:"blocks,omitempty"`
-	Elements  []element.Element `json:"elements"`
+	Name       field.TranslatableField `json:"name"`
+	Version    uint32                  `json:"version,omitempty"`
+	MaxBlocks  *uint8                  `json:"max_blocks,omitempty"`
+	Blocks     []blocks.Schema         `json:"blocks,omitempty"`
+	Elements   []element.Element       `json:"elements"`
+	Presets    []Preset                `json:"presets,omitempty"`
+	Migrations []Migration             `json:"migrations,omitempty"`
+	// Rules validate several element values of the component together
+	Rules []element.Rule `json:"rules,omitempty"`
+
+	visibility  *element.Visibility
+	ruleChecker *element.Rules
 }
 
+// Parse parses the raw component schema without localizing it, so one
+// parsed schema can serve every locale through Localize.
 func Parse(
 	raw jsonmodel.ComponentSchema,
-	locale string,
-	localeProvider locale.LocaleProvider,
 ) (*Schema, error) {
-	elements := make([]element.Element, 0, len(raw.Elements))
-	for _, rawEle := range raw.Elements {
-		ele, err := element.UnmarshalElement(rawEle)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal element: %w", err)
-		}
-		elements = append(elements, ele)
+	elements, err := element.UnmarshalElements(raw.Elements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal element: %w", err)
 	}
 	for _, ele := range elements {
-		ele.SetLocale(locale, localeProvider)
 		if err := ele.Validate(); err != nil {
 			return nil, fmt.Errorf("element %s(%s) validation failed: %w", ele.GetID(), ele.EleType(), err)
 		}
 	}
+	visibility, err := element.NewVisibility(elements)
+	if err != nil {
+		return nil, fmt.Errorf("invalid visible_if: %w", err)
+	}
 	blockSchemas := make([]blocks.Schema, 0, len(raw.Blocks))
 	for _, rawBlock := range raw.Blocks {
-		block, err := blocks.Parse(rawBlock, locale, localeProvider)
+		block, err := blocks.Parse(rawBlock)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse block schema: %w", err)
 		}
 		blockSchemas = append(blockSchemas, *block)
 	}
-	raw.Name.SetLocale(locale, localeProvider)
+	rules := make([]element.Rule, 0, len(raw.Rules))
+	for _, rawRule := range raw.Rules {
+		rules = append(rules, element.Rule(rawRule))
+	}
+	ruleChecker, err := element.NewRules(rules, visibility)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule: %w", err)
+	}
+	presets, err := parsePresets(raw.Version, raw.Presets, elements, visibility, ruleChecker, raw.MaxBlocks, blockSchemas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid preset: %w", err)
+	}
+	migrations, err := parseMigrations(raw.Version, raw.Migrations, blockSchemas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration: %w", err)
+	}
 	return &Schema{
-		Name:      raw.Name.String(),
-		MaxBlocks: raw.MaxBlocks,
-		Blocks:    blockSchemas,
-		Elements:  elements,
+		Name:        raw.Name,
+		Version:     raw.Version,
+		MaxBlocks:   raw.MaxBlocks,
+		Blocks:      blockSchemas,
+		Elements:    elements,
+		Presets:     presets,
+		Migrations:  migrations,
+		Rules:       rules,
+		visibility:  visibility,
+		ruleChecker: ruleChecker,
 	}, nil
 }
+
+// Localize returns a copy of the schema with all translatable fields
+// resolved for the given locale, the receiver is left untouched.
+func (s *Schema) Localize(
+	locale string,
+	localeProvider locale.LocaleProvider,
+) *Schema {
+	elements := make([]element.Element, 0, len(s.Elements))
+	for _, ele := range s.Elements {
+		elements = append(elements, ele.Localize(locale, localeProvider))
+	}
+	blockSchemas := make([]blocks.Schema, 0, len(s.Blocks))
+	for _, blockSchema := range s.Blocks {
+		blockSchemas = append(blockSchemas, *blockSchema.Localize(locale, localeProvider))
+	}
+	presets := make([]Preset, 0, len(s.Presets))
+	for _, preset := range s.Presets {
+		preset.Name = preset.Name.Localize(locale, localeProvider)
+		presets = append(presets, preset)
+	}
+	rules := make([]element.Rule, 0, len(s.Rules))
+	for _, rule := range s.Rules {
+		rule.Message = rule.Message.Localize(locale, localeProvider)
+		rules = append(rules, rule)
+	}
+	return &Schema{
+		Name:        s.Name.Localize(locale, localeProvider),
+		Version:     s.Version,
+		MaxBlocks:   s.MaxBlocks,
+		Blocks:      blockSchemas,
+		Elements:    elements,
+		Presets:     presets,
+		Migrations:  s.Migrations,
+		Rules:       rules,
+		visibility:  s.visibility.Localize(locale, localeProvider),
+		ruleChecker: s.ruleChecker.Localize(locale, localeProvider),
+	}
+}
+
+// Visibility returns the visible_if conditions of the elements parsed by Parse
+func (s *Schema) Visibility() *element.Visibility {
+	return s.visibility
+}
+
+// RuleChecker returns the rules parsed by Parse
+func (s *Schema) RuleChecker() *element.Rules {
+	return s.ruleChecker
+}
+
+// IsElementVisible reports whether element id is visible with the settings of
+// the component according to visible_if, editors hide invisible elements and
+// validation skips them
+func (s *Schema) IsElementVisible(id string, settings Settings) bool {
+	return s.visibility.IsVisible(id, settings.ElementSettings)
+}
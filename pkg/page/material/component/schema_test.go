@@ -0,0 +1,130 @@
+package component
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+var visibleIfSchemaRaw = []byte(`{
+	"name": "title",
+	"elements": [
+		{"type": "range", "id": "padding_top", "label": "Top", "min": 0, "max": 100, "default": 36},
+		{"type": "range", "id": "padding_bottom", "label": "Bottom", "min": 0, "max": 100, "default": 36, "visible_if": "padding_top >= 10"}
+	],
+	"blocks": [{"type": "sub_title", "name": "Sub title", "elements": [
+		{"type": "text", "id": "sub_title_text", "label": "Text", "default": ""},
+		{"type": "range", "id": "sub_title_opacity", "label": "Opacity", "min": 0, "max": 100, "default": 100, "visible_if": "sub_title_text != ''"}
+	]}]
+}`)
+
+func TestIsElementVisible(t *testing.T) {
+	var raw jsonmodel.ComponentSchema
+	if err := json.Unmarshal(visibleIfSchemaRaw, &raw); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	schema, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	// localized copies keep the conditions parsed once by Parse
+	localized := schema.Localize("en-US", locale.NewJSONProvider([]byte(`{}`)))
+
+	tests := []struct {
+		name           string
+		paddingTop     int64
+		subTitleText   string
+		paddingVisible bool
+		opacityVisible bool
+	}{
+		{name: "visible", paddingTop: 36, subTitleText: "Sub title", paddingVisible: true, opacityVisible: true},
+		{name: "hidden", paddingTop: 5, subTitleText: "", paddingVisible: false, opacityVisible: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subTitleText, err := jsonx.NewString(tt.subTitleText)
+			if err != nil {
+				t.Fatalf("failed to encode sub title: %v", err)
+			}
+			settings := Settings{ElementSettings: map[string]jsonx.JSONValue{"padding_top": *jsonx.NewNumber(tt.paddingTop)}}
+			blockSettings := blocks.Settings{ElementSettings: map[string]jsonx.JSONValue{"sub_title_text": *subTitleText}}
+
+			for _, s := range []*Schema{schema, localized} {
+				if got := s.IsElementVisible("padding_bottom", settings); got != tt.paddingVisible {
+					t.Fatalf("expected padding_bottom visible %v, got %v", tt.paddingVisible, got)
+				}
+				if got := s.Blocks[0].IsElementVisible("sub_title_opacity", blockSettings); got != tt.opacityVisible {
+					t.Fatalf("expected sub_title_opacity visible %v, got %v", tt.opacityVisible, got)
+				}
+				// elements without visible_if are always visible
+				if !s.IsElementVisible("padding_top", settings) {
+					t.Fatal("expected padding_top to be visible")
+				}
+			}
+		})
+	}
+
+	unknownRef := jsonx.JSONValue{RawMessage: append([]byte(nil), visibleIfSchemaRaw...)}
+	if err := unknownRef.Set("elements.1.visible_if", "padding_left > 0"); err != nil {
+		t.Fatalf("failed to set visible_if: %v", err)
+	}
+	var unknownRaw jsonmodel.ComponentSchema
+	if err := json.Unmarshal(unknownRef.RawMessage, &unknownRaw); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	if _, err := Parse(unknownRaw); err == nil {
+		t.Fatal("expected error for visible_if referencing an unknown element")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		expected string
+	}{
+		{name: "valid", rules: `[{"id": "r", "expr": "padding_top + padding_bottom <= 120", "message": "t:rules.padding_sum"}]`},
+		{name: "unknown element", rules: `[{"id": "r", "expr": "padding_left > 0", "message": "m"}]`, expected: "rule r references unknown element padding_left"},
+		{
+			name:     "duplicated id",
+			rules:    `[{"id": "r", "expr": "padding_top > 0", "message": "m"}, {"id": "r", "expr": "padding_bottom > 0", "message": "m"}]`,
+			expected: "duplicated rule r",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawSchema := jsonx.JSONValue{RawMessage: append([]byte(nil), visibleIfSchemaRaw...)}
+			if err := rawSchema.Set("rules", json.RawMessage(tt.rules)); err != nil {
+				t.Fatalf("failed to set rules: %v", err)
+			}
+			var raw jsonmodel.ComponentSchema
+			if err := json.Unmarshal(rawSchema.RawMessage, &raw); err != nil {
+				t.Fatalf("failed to unmarshal schema: %v", err)
+			}
+			schema, err := Parse(raw)
+			if tt.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Fatalf("expected error containing %q, got %v", tt.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			localized := schema.Localize("en-US", locale.NewJSONProvider([]byte(`{"rules": {"padding_sum": "Paddings are too large"}}`)))
+			if got := localized.Rules[0].Message.String(); got != "Paddings are too large" {
+				t.Fatalf("expected localized rule message, got %s", got)
+			}
+			if got := schema.Rules[0].Message.String(); got != "t:rules.padding_sum" {
+				t.Fatalf("expected the schema to keep its translation key, got %s", got)
+			}
+		})
+	}
+}
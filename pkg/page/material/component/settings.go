@@ -6,11 +6,17 @@ import (
 )
 
 type Settings struct {
-	ID              string                     `json:"id"`
-	Name            string                     `json:"name"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// SchemaVersion is the version of the component schema the settings were
+	// last migrated to, see Schema.Migrate. New settings must carry the current
+	// version, otherwise every migration runs over them.
+	SchemaVersion   uint32                     `json:"schema_version,omitempty"`
 	BlockOrder      []string                   `json:"block_order"`
 	Blocks          map[string]blocks.Settings `json:"blocks"`
 	ElementSettings map[string]jsonx.JSONValue `json:"element_settings"`
+	// Disabled components are validated and kept but not rendered
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 func (s *Settings) GetElementSettingByID(id string) *jsonx.JSONValue {
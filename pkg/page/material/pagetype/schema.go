@@ -0,0 +1,151 @@
+package pagetype
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+)
+
+var ErrViolation = errors.New("page type violation")
+
+// Schema restricts the components of the templates of a page type, e.g. a
+// product page has to contain a product title and accepts no cart
+type Schema struct {
+	Name string `json:"name"`
+	// AllowedComponents lists the accepted component names, empty accepts every component
+	AllowedComponents []string `json:"allowed_components,omitempty"`
+	// RequiredComponents have to be present and enabled at least once
+	RequiredComponents []string `json:"required_components,omitempty"`
+	// MaxInstances limits the enabled components of a name
+	MaxInstances map[string]uint8 `json:"max_instances,omitempty"`
+	// StaticComponents can neither be moved nor removed nor disabled
+	StaticComponents []StaticComponent `json:"static_components,omitempty"`
+}
+
+// StaticComponent pins the component with ID at Position of the order,
+// negative positions count from the end so -1 is the last component
+type StaticComponent struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+// Parse parses the raw page type schema, required and static components have
+// to be allowed and static components must not share a position
+func Parse(raw jsonmodel.PageTypeSchema) (*Schema, error) {
+	if raw.Name == "" {
+		return nil, fmt.Errorf("page type name is empty")
+	}
+	s := &Schema{
+		Name:               raw.Name,
+		AllowedComponents:  raw.AllowedComponents,
+		RequiredComponents: raw.RequiredComponents,
+		MaxInstances:       raw.MaxInstances,
+	}
+	for _, name := range raw.RequiredComponents {
+		if !s.allows(name) {
+			return nil, fmt.Errorf("required component %s is not allowed", name)
+		}
+		if limit, ok := raw.MaxInstances[name]; ok && limit == 0 {
+			return nil, fmt.Errorf("required component %s has max instances 0", name)
+		}
+	}
+
+	staticIDs := make(map[string]struct{}, len(raw.StaticComponents))
+	positions := make(map[int]string, len(raw.StaticComponents))
+	for _, rawStatic := range raw.StaticComponents {
+		if !s.allows(rawStatic.Name) {
+			return nil, fmt.Errorf("static component %s(%s) is not allowed", rawStatic.ID, rawStatic.Name)
+		}
+		if _, ok := staticIDs[rawStatic.ID]; ok {
+			return nil, fmt.Errorf("duplicated static component %s", rawStatic.ID)
+		}
+		staticIDs[rawStatic.ID] = struct{}{}
+		if other, ok := positions[rawStatic.Position]; ok {
+			return nil, fmt.Errorf("static components %s and %s share position %d", other, rawStatic.ID, rawStatic.Position)
+		}
+		positions[rawStatic.Position] = rawStatic.ID
+		s.StaticComponents = append(s.StaticComponents, StaticComponent(rawStatic))
+	}
+	return s, nil
+}
+
+func (s *Schema) allows(name string) bool {
+	return len(s.AllowedComponents) == 0 || slices.Contains(s.AllowedComponents, name)
+}
+
+// Check checks the components of a template against the page type, every
+// violation is reported in a single error wrapping ErrViolation. order is the
+// render order of the page, its missing, disabled and repeated entries are not
+// rendered so they are skipped before counting instances and static positions.
+func (s *Schema) Check(order []string, components map[string]component.Settings) error {
+	violations := make([]error, 0)
+
+	compIDs := make([]string, 0, len(components))
+	for compID := range components {
+		compIDs = append(compIDs, compID)
+	}
+	slices.Sort(compIDs)
+	for _, compID := range compIDs {
+		if name := components[compID].Name; !s.allows(name) {
+			violations = append(violations, fmt.Errorf("component %s(%s) is not allowed", compID, name))
+		}
+	}
+
+	// count the enabled components of the order, each id once
+	instances := make(map[string]uint8)
+	rendered := make([]string, 0, len(order))
+	counted := make(map[string]struct{}, len(order))
+	for _, compID := range order {
+		compSettings, ok := components[compID]
+		if _, seen := counted[compID]; seen || !ok || compSettings.Disabled {
+			continue
+		}
+		counted[compID] = struct{}{}
+		rendered = append(rendered, compID)
+		instances[compSettings.Name]++
+	}
+	for _, name := range s.RequiredComponents {
+		if instances[name] == 0 {
+			violations = append(violations, fmt.Errorf("required component %s is missing", name))
+		}
+	}
+	names := make([]string, 0, len(s.MaxInstances))
+	for name := range s.MaxInstances {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if limit := s.MaxInstances[name]; instances[name] > limit {
+			violations = append(violations, fmt.Errorf("component %s has %d instances, max %d", name, instances[name], limit))
+		}
+	}
+
+	for _, static := range s.StaticComponents {
+		compSettings, ok := components[static.ID]
+		switch {
+		case !ok:
+			violations = append(violations, fmt.Errorf("static component %s is missing", static.ID))
+			continue
+		case compSettings.Name != static.Name:
+			violations = append(violations, fmt.Errorf("static component %s must be %s, got %s", static.ID, static.Name, compSettings.Name))
+		case compSettings.Disabled:
+			violations = append(violations, fmt.Errorf("static component %s can not be disabled", static.ID))
+		}
+		position := static.Position
+		if position < 0 {
+			position += len(rendered)
+		}
+		if position < 0 || position >= len(rendered) || rendered[position] != static.ID {
+			violations = append(violations, fmt.Errorf("static component %s must be at position %d", static.ID, static.Position))
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w of %s: %w", ErrViolation, s.Name, errors.Join(violations...))
+}
@@ -0,0 +1,127 @@
+package pagetype
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+)
+
+func TestSchemaCheck(t *testing.T) {
+	schema, err := Parse(jsonmodel.PageTypeSchema{
+		Name:               "product",
+		AllowedComponents:  []string{"header", "title", "description"},
+		RequiredComponents: []string{"title"},
+		MaxInstances:       map[string]uint8{"description": 2},
+		StaticComponents: []jsonmodel.StaticComponent{
+			{ID: "comp_header", Name: "header", Position: 0},
+			{ID: "comp_title", Name: "title", Position: -1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse page type: %v", err)
+	}
+
+	components := map[string]component.Settings{
+		"comp_header": {ID: "comp_header", Name: "header"},
+		"comp_title":  {ID: "comp_title", Name: "title"},
+		"comp_desc_1": {ID: "comp_desc_1", Name: "description"},
+		"comp_desc_2": {ID: "comp_desc_2", Name: "description"},
+		"comp_desc_3": {ID: "comp_desc_3", Name: "description", Disabled: true},
+		"comp_cart":   {ID: "comp_cart", Name: "cart"},
+	}
+	without := func(ids ...string) map[string]component.Settings {
+		filtered := make(map[string]component.Settings, len(components))
+		for id, settings := range components {
+			filtered[id] = settings
+		}
+		for _, id := range ids {
+			delete(filtered, id)
+		}
+		return filtered
+	}
+
+	tests := []struct {
+		name       string
+		order      []string
+		components map[string]component.Settings
+		expected   []string
+	}{
+		{
+			name:       "valid",
+			order:      []string{"comp_header", "comp_desc_1", "comp_title"},
+			components: without("comp_cart"),
+		},
+		{
+			name:       "disabled, missing and repeated entries take no position",
+			order:      []string{"comp_desc_3", "comp_missing", "comp_header", "comp_desc_1", "comp_header", "comp_title", "comp_desc_3"},
+			components: without("comp_cart"),
+		},
+		{
+			name:       "component not allowed",
+			order:      []string{"comp_header", "comp_title"},
+			components: components,
+			expected:   []string{"component comp_cart(cart) is not allowed"},
+		},
+		{
+			name:       "repeated and disabled instances are not counted",
+			order:      []string{"comp_header", "comp_desc_1", "comp_desc_2", "comp_desc_1", "comp_desc_3", "comp_title"},
+			components: without("comp_cart"),
+		},
+		{
+			name:  "too many instances",
+			order: []string{"comp_header", "comp_desc_1", "comp_desc_2", "comp_desc_3", "comp_title"},
+			components: map[string]component.Settings{
+				"comp_header": {ID: "comp_header", Name: "header"},
+				"comp_title":  {ID: "comp_title", Name: "title"},
+				"comp_desc_1": {ID: "comp_desc_1", Name: "description"},
+				"comp_desc_2": {ID: "comp_desc_2", Name: "description"},
+				"comp_desc_3": {ID: "comp_desc_3", Name: "description"},
+			},
+			expected: []string{"component description has 3 instances, max 2"},
+		},
+		{
+			name:       "static component moved",
+			order:      []string{"comp_header", "comp_title", "comp_desc_1"},
+			components: without("comp_cart"),
+			expected:   []string{"static component comp_title must be at position -1"},
+		},
+		{
+			name:       "static and required component missing",
+			order:      []string{"comp_header", "comp_desc_1"},
+			components: without("comp_cart", "comp_title"),
+			expected:   []string{"required component title is missing", "static component comp_title is missing"},
+		},
+		{
+			name:  "static component disabled",
+			order: []string{"comp_header", "comp_title"},
+			components: map[string]component.Settings{
+				"comp_header": {ID: "comp_header", Name: "header", Disabled: true},
+				"comp_title":  {ID: "comp_title", Name: "title"},
+			},
+			expected: []string{"static component comp_header can not be disabled", "static component comp_header must be at position 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Check(tt.order, tt.components)
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrViolation) {
+				t.Fatalf("expected page type violation, got %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected error containing %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+var ErrUnknownElementSettings = errors.New("unknown element settings")
+
+// UnknownSettingsPolicy decides what Validate does with element settings not
+// declared by the schema of their component or block
+type UnknownSettingsPolicy int
+
+const (
+	// UnknownSettingsKeep keeps undeclared settings as they are, it is the default
+	UnknownSettingsKeep UnknownSettingsPolicy = iota
+	// UnknownSettingsPrune removes undeclared settings
+	UnknownSettingsPrune
+	// UnknownSettingsStrict fails Validate with ErrUnknownElementSettings
+	UnknownSettingsStrict
+)
+
+// SetUnknownSettingsPolicy sets the policy for undeclared element settings,
+// UnknownSettingsKeep by default
+func (t *JSONTemplate) SetUnknownSettingsPolicy(policy UnknownSettingsPolicy) {
+	t.settingsMode.unknown = policy
+}
+
+// SetFillDefaults makes Validate write the default of every declared element
+// missing from the settings, so ToProps always outputs every element.
+// Translatable defaults are resolved through the locale and then handled like
+// stored values.
+func (t *JSONTemplate) SetFillDefaults(localeCode string, localeProvider locale.LocaleProvider) {
+	t.settingsMode.fillDefaults = true
+	t.settingsMode.localeCode = localeCode
+	t.settingsMode.localeProvider = localeProvider
+}
+
+type settingsMode struct {
+	unknown        UnknownSettingsPolicy
+	fillDefaults   bool
+	localeCode     string
+	localeProvider locale.LocaleProvider
+}
+
+// validateElementSettings runs the settings of the declared elements through
+// handlers and returns the validated settings, scope names the owner for
+// error messages. The elements a visible_if condition references are handled
+// first, so visibility is decided on validated values as ToProps does. Values
+// of elements hidden by visible_if are kept unchecked. A nil map stays nil
+// when nothing is kept.
+func validateElementSettings(
+	scope string,
+	elements []element.Element,
+	visibility *element.Visibility,
+	settings map[string]jsonx.JSONValue,
+	mode settingsMode,
+	handlers []ElementValueHandler,
+) (map[string]jsonx.JSONValue, error) {
+	validated := make(map[string]jsonx.JSONValue, len(settings))
+	declared := make(map[string]element.Element, len(elements))
+	for _, ele := range elements {
+		declared[ele.GetID()] = ele
+	}
+
+	// visible_if references are acyclic, checked by NewVisibility
+	handled := make(map[string]struct{}, len(elements))
+	var handle func(ele element.Element) error
+	handle = func(ele element.Element) error {
+		if _, ok := handled[ele.GetID()]; ok {
+			return nil
+		}
+		handled[ele.GetID()] = struct{}{}
+		for _, id := range visibility.Dependencies(ele.GetID()) {
+			if dep, ok := declared[id]; ok {
+				if err := handle(dep); err != nil {
+					return err
+				}
+			}
+		}
+
+		val, ok := settings[ele.GetID()]
+		if !ok {
+			if !mode.fillDefaults {
+				return nil
+			}
+			// elements without a default have nothing to fill, filled defaults
+			// go through the handlers like stored values
+			if val = ele.Localize(mode.localeCode, mode.localeProvider).GetDefault(); len(val.RawMessage) == 0 {
+				return nil
+			}
+		}
+		if !visibility.IsVisible(ele.GetID(), validated) {
+			validated[ele.GetID()] = val
+			return nil
+		}
+		var err error
+		for _, handler := range handlers {
+			val, err = handler.Handle(ele, val, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s element %s value handling failed: %w", scope, ele.GetID(), err)
+		}
+		validated[ele.GetID()] = val
+		return nil
+	}
+	for _, ele := range elements {
+		if err := handle(ele); err != nil {
+			return nil, err
+		}
+	}
+
+	unknown := make([]string, 0)
+	for id, val := range settings {
+		if _, ok := declared[id]; ok {
+			continue
+		}
+		unknown = append(unknown, id)
+		if mode.unknown == UnknownSettingsKeep {
+			validated[id] = val
+		}
+	}
+	if len(unknown) > 0 && mode.unknown == UnknownSettingsStrict {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownElementSettings, scope, strings.Join(unknown, ", "))
+	}
+
+	if settings == nil && len(validated) == 0 {
+		return nil, nil
+	}
+	return validated, nil
+}
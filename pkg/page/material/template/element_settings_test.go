@@ -0,0 +1,263 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+// overlayElements declares an opacity shown only while the overlay is on
+func overlayElements(t *testing.T) ([]element.Element, *element.Visibility) {
+	t.Helper()
+	elements, err := element.UnmarshalElements([]jsonx.JSONValue{
+		// opacity comes first so it is declared before its dependency
+		{RawMessage: json.RawMessage(`{"type": "range", "id": "opacity", "label": "Opacity", "min": 0, "max": 100, "default": 50, "visible_if": "show_overlay == 'on'"}`)},
+		{RawMessage: json.RawMessage(`{"type": "select", "id": "show_overlay", "label": "Overlay", "default": "on", "options": [
+			{"value": "on", "label": "On"}, {"value": "off", "label": "Off"}
+		]}`)},
+	})
+	if err != nil {
+		t.Fatalf("failed to unmarshal elements: %v", err)
+	}
+	visibility, err := element.NewVisibility(elements)
+	if err != nil {
+		t.Fatalf("failed to parse visibility: %v", err)
+	}
+	return elements, visibility
+}
+
+func TestValidateElementSettingsVisibleIf(t *testing.T) {
+	elements, visibility := overlayElements(t)
+
+	tests := []struct {
+		name            string
+		settings        string
+		handlers        []ElementValueHandler
+		expectedOpacity string
+		wantErr         bool
+	}{
+		{
+			name:            "hidden by a valid dependency",
+			settings:        `{"show_overlay": "off", "opacity": 500}`,
+			handlers:        []ElementValueHandler{NewElementValueChecker()},
+			expectedOpacity: "500",
+		},
+		{
+			// the default setter turns the dependency on, so opacity is checked
+			name:            "shown by a fixed dependency",
+			settings:        `{"show_overlay": "bogus", "opacity": 500}`,
+			handlers:        []ElementValueHandler{NewElementValueChecker(), NewElementValueDefaultSetter("en-US", nil)},
+			expectedOpacity: "50",
+		},
+		{
+			name:     "invalid dependency",
+			settings: `{"show_overlay": "bogus", "opacity": 500}`,
+			handlers: []ElementValueHandler{NewElementValueChecker()},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings map[string]jsonx.JSONValue
+			if err := json.Unmarshal([]byte(tt.settings), &settings); err != nil {
+				t.Fatalf("failed to unmarshal settings: %v", err)
+			}
+			validated, err := validateElementSettings("component comp", elements, visibility, settings, settingsMode{}, tt.handlers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := validated["opacity"].String(); got != tt.expectedOpacity {
+				t.Fatalf("expected opacity %s, got %s", tt.expectedOpacity, got)
+			}
+			// ToProps decides visibility on the validated values the same way
+			for _, ele := range elements {
+				if !visibility.IsVisible(ele.GetID(), validated) {
+					continue
+				}
+				if _, err := ele.ToLiquid(validated[ele.GetID()]); err != nil {
+					t.Fatalf("expected visible element %s to render, got %v", ele.GetID(), err)
+				}
+			}
+		})
+	}
+}
+
+// upperHandler marks the values it handled
+type upperHandler struct{}
+
+func (upperHandler) Handle(ele element.Element, val jsonx.JSONValue, err error) (jsonx.JSONValue, error) {
+	if err != nil || !val.IsString() {
+		return val, err
+	}
+	upper, err := jsonx.NewString(strings.ToUpper(val.String()))
+	if err != nil {
+		return val, err
+	}
+	return *upper, nil
+}
+
+func TestValidateElementSettingsFillDefaults(t *testing.T) {
+	elements, visibility := overlayElements(t)
+	mode := settingsMode{fillDefaults: true, localeCode: "en-US"}
+	handlers := []ElementValueHandler{NewElementValueChecker(), upperHandler{}}
+
+	validated, err := validateElementSettings("component comp", elements, visibility, map[string]jsonx.JSONValue{}, mode, handlers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := validated["show_overlay"].String(); got != "ON" {
+		t.Fatalf("expected filled default to go through the handlers, got %s", got)
+	}
+	// the handled default is no longer "on", so opacity is filled but hidden
+	if got := validated["opacity"].String(); got != "50" {
+		t.Fatalf("expected hidden default to be filled unchecked, got %s", got)
+	}
+}
+
+func TestElementSettingsModes(t *testing.T) {
+	titleSchemaRaw := mutate(t, []byte(pageSchemasRaw["title"]),
+		mutation{path: "blocks.1.elements.0.default", value: "t:title.sub_title"},
+	)
+	schemaProvider := pageSchemas(t, map[string][]byte{"title": titleSchemaRaw})
+	localeProvider := locale.NewJSONProvider([]byte(`{"title": {"sub_title": "Localized sub title"}}`))
+
+	page := mutate(t, pageRaw,
+		mutation{path: "components.comp_title.element_settings.padding_top", value: 1000},
+		mutation{path: "components.comp_title.element_settings.padding_topp", value: 12},
+		mutation{path: "components.comp_title.blocks.blc_sub_title.element_settings", value: map[string]any{"sub_title_colour": "red"}},
+	)
+
+	validate := func(t *testing.T, policy UnknownSettingsPolicy, fill bool) (*JSONTemplate, error) {
+		tpl := parsePage(t, page, schemaProvider)
+		tpl.SetUnknownSettingsPolicy(policy)
+		if fill {
+			tpl.SetFillDefaults("en-US", localeProvider)
+		}
+		return tpl, tpl.Validate(NewElementValueChecker(), NewElementValueDefaultSetter("en-US", localeProvider))
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		tpl, err := validate(t, UnknownSettingsKeep, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compSettings := tpl.Components["comp_title"]
+		if got := compSettings.ElementSettings["padding_top"].String(); got != "36" {
+			t.Fatalf("expected invalid value to be replaced by default 36, got %s", got)
+		}
+		if _, ok := compSettings.ElementSettings["padding_topp"]; !ok {
+			t.Fatal("expected unknown setting to be kept")
+		}
+		if _, ok := compSettings.Blocks["blc_sub_title"].ElementSettings["sub_title_opacity"]; ok {
+			t.Fatal("expected missing setting not to be filled")
+		}
+	})
+
+	t.Run("prune and fill", func(t *testing.T) {
+		tpl, err := validate(t, UnknownSettingsPrune, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		compSettings := tpl.Components["comp_title"]
+		if _, ok := compSettings.ElementSettings["padding_topp"]; ok {
+			t.Fatal("expected unknown component setting to be pruned")
+		}
+		blockSettings := compSettings.Blocks["blc_sub_title"].ElementSettings
+		if _, ok := blockSettings["sub_title_colour"]; ok {
+			t.Fatal("expected unknown block setting to be pruned")
+		}
+		if got := blockSettings["sub_title_opacity"].String(); got != "100" {
+			t.Fatalf("expected missing setting to be filled with 100, got %s", got)
+		}
+		if got := blockSettings["sub_title_text"].String(); got != "Localized sub title" {
+			t.Fatalf("expected translatable default to be filled localized, got %s", got)
+		}
+
+		props, err := tpl.ToProps("en-US")
+		if err != nil {
+			t.Fatalf("failed to convert template to props: %v", err)
+		}
+		blockProps := props["comp_title"].(map[string]any)["blocks"].([]map[string]any)
+		if _, ok := blockProps[1]["settings"].(map[string]any)["sub_title_opacity"]; !ok {
+			t.Fatal("expected filled setting in props")
+		}
+		if _, ok := blockProps[1]["settings"].(map[string]any)["sub_title_text"]; !ok {
+			t.Fatal("expected filled translatable default in props")
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := validate(t, UnknownSettingsStrict, false)
+		if !errors.Is(err, ErrUnknownElementSettings) {
+			t.Fatalf("expected unknown element settings error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "padding_topp") {
+			t.Fatalf("expected error to name the unknown setting, got %v", err)
+		}
+	})
+}
+
+func TestValidateVisibleIf(t *testing.T) {
+	titleSchemaRaw := mutate(t, []byte(pageSchemasRaw["title"]),
+		mutation{path: "elements.1.visible_if", value: "padding_top >= 10"},
+		mutation{path: "blocks.1.elements.1.visible_if", value: `sub_title_text != ""`},
+	)
+	schemaProvider := pageSchemas(t, map[string][]byte{"title": titleSchemaRaw})
+
+	tests := []struct {
+		name          string
+		paddingTop    int
+		paddingBottom any
+		subTitleText  string
+		wantErr       bool
+	}{
+		{name: "visible and invalid", paddingTop: 36, paddingBottom: 1000, subTitleText: "Sub title", wantErr: true},
+		{name: "hidden values are not checked", paddingTop: 5, paddingBottom: 1000, subTitleText: ""},
+		{name: "hidden values of the wrong type", paddingTop: 5, paddingBottom: "abc", subTitleText: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := mutate(t, pageRaw,
+				mutation{path: "components.comp_title.element_settings.padding_top", value: tt.paddingTop},
+				mutation{path: "components.comp_title.element_settings.padding_bottom", value: tt.paddingBottom},
+				mutation{path: "components.comp_title.blocks.blc_sub_title.element_settings.sub_title_text", value: tt.subTitleText},
+				mutation{path: "components.comp_title.blocks.blc_sub_title.element_settings.sub_title_opacity", value: 1000},
+			)
+			tpl := parsePage(t, page, schemaProvider)
+			err := tpl.Validate(NewElementValueChecker())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// hidden values are kept but not rendered
+			if _, ok := tpl.Components["comp_title"].ElementSettings["padding_bottom"]; !ok {
+				t.Fatal("expected hidden value to be kept")
+			}
+			props, err := tpl.ToProps("en-US")
+			if err != nil {
+				t.Fatalf("failed to convert template to props: %v", err)
+			}
+			compProps := props["comp_title"].(map[string]any)
+			if _, ok := compProps["settings"].(map[string]any)["padding_bottom"]; ok {
+				t.Fatal("expected hidden element not to be rendered")
+			}
+			blockProps := compProps["blocks"].([]map[string]any)
+			if _, ok := blockProps[1]["settings"].(map[string]any)["sub_title_opacity"]; ok {
+				t.Fatal("expected hidden block element not to be rendered")
+			}
+		})
+	}
+}
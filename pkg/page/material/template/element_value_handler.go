@@ -5,6 +5,7 @@ import (
 
 	"github.com/leeseika/cv-demo/pkg/jsonx"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
 type (
@@ -13,15 +14,24 @@ type (
 	}
 
 	elementValueChecker       struct{}
-	elementValueDefaultSetter struct{}
+	elementValueDefaultSetter struct {
+		localeCode     string
+		localeProvider locale.LocaleProvider
+	}
 )
 
 func NewElementValueChecker() ElementValueHandler {
 	return &elementValueChecker{}
 }
 
-func NewElementValueDefaultSetter() ElementValueHandler {
-	return &elementValueDefaultSetter{}
+// NewElementValueDefaultSetter replaces invalid values with the element
+// default. Settings hold plain values, so translatable defaults are resolved
+// for localeCode before they are written.
+func NewElementValueDefaultSetter(localeCode string, localeProvider locale.LocaleProvider) ElementValueHandler {
+	return &elementValueDefaultSetter{
+		localeCode:     localeCode,
+		localeProvider: localeProvider,
+	}
 }
 
 func (evc *elementValueChecker) Handle(ele element.Element, val jsonx.JSONValue, prevErr error) (jsonx.JSONValue, error) {
@@ -42,6 +52,6 @@ func (evds *elementValueDefaultSetter) Handle(ele element.Element, val jsonx.JSO
 		return val, nil
 	}
 
-	defaultVal := ele.GetDefault()
+	defaultVal := ele.Localize(evds.localeCode, evds.localeProvider).GetDefault()
 	return defaultVal, nil
 }
@@ -0,0 +1,36 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
+)
+
+// fingerprintContent is everything bound to a template that ToProps renders
+type fingerprintContent struct {
+	Template *JSONTemplate            `json:"template"`
+	Theme    theme.Settings           `json:"theme,omitempty"`
+	Layout   *Layout                  `json:"layout,omitempty"`
+	Groups   map[string]*JSONTemplate `json:"groups,omitempty"`
+}
+
+// Fingerprint returns a stable content hash of the template, templates that
+// are semantically equal share a fingerprint regardless of key order or
+// whitespace. The bound theme settings and the section groups of the bound
+// layout are part of it, the component schemas are not. Validate the template
+// first so defaults and sanitized values are part of the fingerprint, a render
+// cache key also needs the locale code and the schema versions.
+func (t *JSONTemplate) Fingerprint() (string, error) {
+	content := fingerprintContent{Template: t, Theme: t.themeSettings}
+	if t.Layout != "" && t.layout != nil {
+		content.Layout = t.layout
+		content.Groups = t.layout.groups
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal json template: %w", err)
+	}
+	return jsonx.Hash(raw)
+}
@@ -0,0 +1,54 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
+)
+
+func TestFingerprintBindings(t *testing.T) {
+	newLayout := func(title string) *Layout {
+		groups := map[string]*JSONTemplate{
+			"header": {
+				Name: "header",
+				Components: map[string]component.Settings{"comp_header": {
+					ID:              "comp_header",
+					ElementSettings: map[string]jsonx.JSONValue{"title": {RawMessage: json.RawMessage(title)}},
+				}},
+				Order: []string{"comp_header"},
+			},
+		}
+		layout, err := ParseLayout([]byte(`{"name": "default", "sections": [{"group": "header"}, {"slot": "main"}]}`), groups)
+		if err != nil {
+			t.Fatalf("failed to parse layout: %v", err)
+		}
+		return layout
+	}
+	newTemplate := func(themeColor string, layout *Layout) *JSONTemplate {
+		tpl := &JSONTemplate{Name: "page", Layout: "default", Order: []string{}}
+		tpl.SetThemeSettings(nil, theme.Settings{"color": {RawMessage: json.RawMessage(themeColor)}})
+		tpl.SetLayout(layout)
+		return tpl
+	}
+	fingerprint := func(tpl *JSONTemplate) string {
+		f, err := tpl.Fingerprint()
+		if err != nil {
+			t.Fatalf("failed to fingerprint template: %v", err)
+		}
+		return f
+	}
+
+	base := fingerprint(newTemplate(`"#000"`, newLayout(`"Shop"`)))
+	if got := fingerprint(newTemplate(`"#000"`, newLayout(`"Shop"`))); got != base {
+		t.Fatalf("expected stable fingerprint %s, got %s", base, got)
+	}
+	if got := fingerprint(newTemplate(`"#fff"`, newLayout(`"Shop"`))); got == base {
+		t.Fatal("expected theme settings to change the fingerprint")
+	}
+	if got := fingerprint(newTemplate(`"#000"`, newLayout(`"Other Shop"`))); got == base {
+		t.Fatal("expected section groups to change the fingerprint")
+	}
+}
@@ -0,0 +1,144 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrIntegrity = errors.New("template integrity check failed")
+
+// IntegrityPolicy decides what Validate does with the entries of an integrity issue
+type IntegrityPolicy int
+
+const (
+	// IntegrityDrop removes the offending entries, it is the default. Entries
+	// whose settings id differs from the map key are kept with the key as id.
+	IntegrityDrop IntegrityPolicy = iota
+	// IntegrityKeep keeps the offending entries as they are, orphans are
+	// validated but not rendered and do not count towards block limits
+	IntegrityKeep
+	// IntegrityError fails Validate with ErrIntegrity listing all issues
+	IntegrityError
+)
+
+type IntegrityIssueKind string
+
+const (
+	// IntegrityOrphan is an entry of the map missing from the order
+	IntegrityOrphan IntegrityIssueKind = "orphan"
+	// IntegrityDangling is an order entry without settings in the map
+	IntegrityDangling IntegrityIssueKind = "dangling"
+	// IntegrityDuplicate is a repeated order entry
+	IntegrityDuplicate IntegrityIssueKind = "duplicate"
+	// IntegrityIDMismatch is an entry whose settings id differs from its map key
+	IntegrityIDMismatch IntegrityIssueKind = "id_mismatch"
+)
+
+// IntegrityIssue is an integrity problem of the components of a template or
+// of the blocks of a component or block, Scope names the parent
+type IntegrityIssue struct {
+	Kind  IntegrityIssueKind
+	Scope string
+	ID    string
+}
+
+func (i IntegrityIssue) String() string {
+	return fmt.Sprintf("%s: %s %s", i.Scope, i.Kind, i.ID)
+}
+
+// SetIntegrityPolicy sets the policy applied by Validate, IntegrityDrop by default
+func (t *JSONTemplate) SetIntegrityPolicy(policy IntegrityPolicy) {
+	t.integrityPolicy = policy
+}
+
+// IntegrityIssues returns the issues found by the last Validate, a failed
+// Validate reports the issues found before it stopped
+func (t *JSONTemplate) IntegrityIssues() []IntegrityIssue {
+	return t.integrityIssues
+}
+
+type integrityChecker struct {
+	policy IntegrityPolicy
+	issues []IntegrityIssue
+}
+
+// orderEntry is an entry to process while walking an order
+type orderEntry struct {
+	id string
+	// orphan entries are in the map but not in the order
+	orphan bool
+	// ghost entries are dangling or duplicate order entries kept by IntegrityKeep,
+	// they stay in the order but have nothing to validate
+	ghost bool
+	// fixID entries get the map key as settings id
+	fixID bool
+}
+
+func (c *integrityChecker) report(kind IntegrityIssueKind, scope, id string) {
+	c.issues = append(c.issues, IntegrityIssue{Kind: kind, Scope: scope, ID: id})
+}
+
+// plan checks order against the map keys and returns the entries to process,
+// ids maps every key to the id of its settings
+func (c *integrityChecker) plan(scope string, order []string, ids map[string]string) []orderEntry {
+	keep := c.policy == IntegrityKeep
+	entries := make([]orderEntry, 0, len(order))
+	seen := make(map[string]struct{}, len(order))
+	for _, id := range order {
+		if _, ok := seen[id]; ok {
+			c.report(IntegrityDuplicate, scope, id)
+			if keep {
+				entries = append(entries, orderEntry{id: id, ghost: true})
+			}
+			continue
+		}
+		seen[id] = struct{}{}
+
+		settingsID, ok := ids[id]
+		if !ok {
+			c.report(IntegrityDangling, scope, id)
+			if keep {
+				entries = append(entries, orderEntry{id: id, ghost: true})
+			}
+			continue
+		}
+		// the map key is what the order refers to, so mismatched entries are
+		// kept rather than losing their settings
+		mismatch := settingsID != id
+		if mismatch {
+			c.report(IntegrityIDMismatch, scope, id)
+		}
+		entries = append(entries, orderEntry{id: id, fixID: mismatch && c.policy == IntegrityDrop})
+	}
+
+	orphans := make([]string, 0)
+	for id := range ids {
+		if _, ok := seen[id]; !ok {
+			orphans = append(orphans, id)
+		}
+	}
+	slices.Sort(orphans)
+	for _, id := range orphans {
+		c.report(IntegrityOrphan, scope, id)
+		if ids[id] != id {
+			c.report(IntegrityIDMismatch, scope, id)
+		}
+		if keep {
+			entries = append(entries, orderEntry{id: id, orphan: true})
+		}
+	}
+	return entries
+}
+
+func (c *integrityChecker) err() error {
+	if c.policy != IntegrityError || len(c.issues) == 0 {
+		return nil
+	}
+	issues := make([]string, 0, len(c.issues))
+	for _, issue := range c.issues {
+		issues = append(issues, issue.String())
+	}
+	return fmt.Errorf("%w: %s", ErrIntegrity, strings.Join(issues, "; "))
+}
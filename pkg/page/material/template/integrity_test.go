@@ -0,0 +1,167 @@
+package template
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIntegrityPlan(t *testing.T) {
+	order := []string{"a", "missing", "b", "a", "c"}
+	// b has a mismatched id, c has none at all, d is not in the order
+	ids := map[string]string{"a": "a", "b": "x", "c": "", "d": "d"}
+	expectedIssues := []string{
+		"scope: dangling missing",
+		"scope: id_mismatch b",
+		"scope: duplicate a",
+		"scope: id_mismatch c",
+		"scope: orphan d",
+	}
+
+	tests := []struct {
+		name     string
+		policy   IntegrityPolicy
+		expected []string
+		wantErr  bool
+	}{
+		{name: "drop", policy: IntegrityDrop, expected: []string{"a", "b(fix)", "c(fix)"}},
+		{name: "keep", policy: IntegrityKeep, expected: []string{"a", "missing(ghost)", "b", "a(ghost)", "c", "d(orphan)"}},
+		{name: "error", policy: IntegrityError, expected: []string{"a", "b", "c"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &integrityChecker{policy: tt.policy}
+			entries := make([]string, 0)
+			for _, entry := range c.plan("scope", order, ids) {
+				switch {
+				case entry.ghost:
+					entries = append(entries, entry.id+"(ghost)")
+				case entry.orphan:
+					entries = append(entries, entry.id+"(orphan)")
+				case entry.fixID:
+					entries = append(entries, entry.id+"(fix)")
+				default:
+					entries = append(entries, entry.id)
+				}
+			}
+			if strings.Join(entries, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("expected entries %v, got %v", tt.expected, entries)
+			}
+
+			issues := make([]string, 0, len(c.issues))
+			for _, issue := range c.issues {
+				issues = append(issues, issue.String())
+			}
+			if strings.Join(issues, "\n") != strings.Join(expectedIssues, "\n") {
+				t.Fatalf("expected issues %v, got %v", expectedIssues, issues)
+			}
+			if err := c.err(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIntegrityPolicies(t *testing.T) {
+	schemaProvider := pageSchemas(t, nil)
+	page := mutate(t, pageRaw,
+		mutation{path: "components.comp_orphan", value: map[string]any{
+			"id": "comp_orphan", "name": "text", "element_settings": map[string]any{"content": "Orphan"},
+		}},
+		mutation{path: "order.-1", value: "comp_missing"},
+		mutation{path: "order.-1", value: "comp_title"},
+		mutation{path: "components.comp_title.blocks.blc_sub_title.id", value: "blc_other"},
+		mutation{path: "components.comp_text.blocks.blc_orphan_line", value: map[string]any{"id": "blc_orphan_line", "type": "line"}},
+	)
+	expectedIssues := []string{
+		"template: dangling comp_missing",
+		"template: duplicate comp_title",
+		"template: orphan comp_orphan",
+		"component comp_title: id_mismatch blc_sub_title",
+		"component comp_text: orphan blc_orphan_line",
+	}
+
+	validate := func(t *testing.T, policy IntegrityPolicy) (*JSONTemplate, error) {
+		tpl := parsePage(t, page, schemaProvider)
+		tpl.SetIntegrityPolicy(policy)
+		err := tpl.Validate(NewElementValueChecker(), NewElementValueDefaultSetter("en-US", nil))
+		issues := make([]string, 0, len(tpl.IntegrityIssues()))
+		for _, issue := range tpl.IntegrityIssues() {
+			issues = append(issues, issue.String())
+		}
+		if strings.Join(issues, "\n") != strings.Join(expectedIssues, "\n") {
+			t.Fatalf("expected issues %v, got %v", expectedIssues, issues)
+		}
+		return tpl, err
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		tpl, err := validate(t, IntegrityDrop)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(tpl.Order, ",") != "comp_title,comp_text" {
+			t.Fatalf("expected dangling and duplicate entries to be dropped, got %v", tpl.Order)
+		}
+		if _, ok := tpl.Components["comp_orphan"]; ok {
+			t.Fatal("expected orphan component to be dropped")
+		}
+		if got := tpl.Components["comp_title"].Blocks["blc_sub_title"].ID; got != "blc_sub_title" {
+			t.Fatalf("expected mismatched block to be kept with its key as id, got %q", got)
+		}
+		if _, ok := tpl.Components["comp_text"].Blocks["blc_orphan_line"]; ok {
+			t.Fatal("expected orphan block to be dropped")
+		}
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		tpl, err := validate(t, IntegrityKeep)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(tpl.Order, ",") != "comp_title,comp_text,comp_missing,comp_title" {
+			t.Fatalf("expected order to be kept, got %v", tpl.Order)
+		}
+		if _, ok := tpl.Components["comp_orphan"]; !ok {
+			t.Fatal("expected orphan component to be kept")
+		}
+		if _, ok := tpl.Components["comp_text"].Blocks["blc_orphan_line"]; !ok {
+			t.Fatal("expected orphan block to be kept")
+		}
+		if got := tpl.Components["comp_title"].Blocks["blc_sub_title"].ID; got != "blc_other" {
+			t.Fatalf("expected mismatched block id to be kept, got %q", got)
+		}
+
+		props, err := tpl.ToProps("en-US")
+		if err != nil {
+			t.Fatalf("failed to convert template to props: %v", err)
+		}
+		if _, ok := props["comp_orphan"]; ok {
+			t.Fatal("expected orphan component not to be rendered")
+		}
+		if order := props["order"].([]string); strings.Join(order, ",") != "comp_title,comp_text" {
+			t.Fatalf("expected kept entries to be rendered once, got %v", order)
+		}
+		blockProps := props["comp_text"].(map[string]any)["blocks"].([]map[string]any)
+		if len(blockProps) != 1 {
+			t.Fatalf("expected orphan block not to be rendered, got %d blocks", len(blockProps))
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tpl, err := validate(t, IntegrityError)
+		if !errors.Is(err, ErrIntegrity) {
+			t.Fatalf("expected integrity error, got %v", err)
+		}
+		if strings.Join(tpl.Order, ",") != "comp_title,comp_text,comp_missing,comp_title" {
+			t.Fatalf("expected failed validation to leave the order untouched, got %v", tpl.Order)
+		}
+		if _, ok := tpl.Components["comp_orphan"]; !ok {
+			t.Fatal("expected failed validation to leave the components untouched")
+		}
+		if got := tpl.Components["comp_title"].Blocks["blc_sub_title"].ID; got != "blc_other" {
+			t.Fatalf("expected failed validation to leave the block id untouched, got %q", got)
+		}
+	})
+}
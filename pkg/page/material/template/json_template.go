@@ -3,19 +3,45 @@ package template
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 
-	"github.com/leeseika/cv-demo/pkg/jsonx"
 	"github.com/leeseika/cv-demo/pkg/page/material/component"
 	"github.com/leeseika/cv-demo/pkg/page/material/component/blocks"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
 	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
 )
 
 type JSONTemplate struct {
-	Name       string                        `json:"name"`
+	Name string `json:"name"`
+	// PageType names the page type restricting the components, see SetPageType
+	PageType   string                        `json:"page_type,omitempty"`
 	Components map[string]component.Settings `json:"components"`
 	Order      []string                      `json:"order"`
+	// Layout names the layout the page is rendered in, Slots assigns
+	// components of the order to its slots, see SetLayout
+	Layout string              `json:"layout,omitempty"`
+	Slots  map[string][]string `json:"slots,omitempty"`
 
+	layout         *Layout                                 `json:"-"`
+	pageType       *pagetype.Schema                        `json:"-"`
 	schemaProvider componentschema.ComponentSchemaProvider `json:"-"`
+	themeSchema    *theme.ThemeSettingsSchema              `json:"-"`
+	themeSettings  theme.Settings                          `json:"-"`
+
+	integrityPolicy  IntegrityPolicy             `json:"-"`
+	integrityIssues  []IntegrityIssue            `json:"-"`
+	settingsMode     settingsMode                `json:"-"`
+	migrationChanges []component.MigrationChange `json:"-"`
+}
+
+// validation carries the options and findings of a single Validate run
+type validation struct {
+	integrity  integrityChecker
+	mode       settingsMode
+	migrations []component.MigrationChange
 }
 
 func ParseJSON(
@@ -40,179 +66,415 @@ func (t *JSONTemplate) Validate(
 		return fmt.Errorf("schema provider is nil")
 	}
 
+	v := &validation{
+		integrity: integrityChecker{policy: t.integrityPolicy},
+		mode:      t.settingsMode,
+	}
+	// the issues found so far are kept when a check fails
+	defer func() { t.integrityIssues = v.integrity.issues }()
+	if err := checkReservedComponentIDs("template", t.Components, t.Order); err != nil {
+		return err
+	}
+	compIDs := make(map[string]string, len(t.Components))
+	for compID, compSettings := range t.Components {
+		compIDs[compID] = compSettings.ID
+	}
+
 	validatedComponents := make(map[string]component.Settings)
 	validatedComponentOrder := make([]string, 0, len(t.Order))
-	for _, compID := range t.Order {
-		compSettings, ok := t.Components[compID]
-		if !ok {
+	for _, entry := range v.integrity.plan("template", t.Order, compIDs) {
+		if entry.ghost {
+			validatedComponentOrder = append(validatedComponentOrder, entry.id)
 			continue
 		}
-		compSchema, err := t.schemaProvider.Get(compSettings.Name)
+		compSettings := t.Components[entry.id]
+		if entry.fixID {
+			compSettings.ID = entry.id
+		}
+		compSettings, err := validateComponent(t.schemaProvider, v, entry.id, compSettings, handlers)
 		if err != nil {
-			return fmt.Errorf("failed to get schema for component %s: %w", compSettings.Name, err)
+			return err
 		}
-		// handle element settings of component
-		for _, ele := range compSchema.Elements {
-			eleVal := compSettings.GetElementSettingByID(ele.GetID())
-			if eleVal == nil {
-				continue
-			}
-			var err error
-			for _, handler := range handlers {
-				var handledEleVal jsonx.JSONValue
-				handledEleVal, err = handler.Handle(ele, *eleVal, err)
-				*eleVal = handledEleVal
-			}
-			if err != nil {
-				return fmt.Errorf("component %s element %s value handling failed: %w", compID, ele.GetID(), err)
-			}
+
+		validatedComponents[entry.id] = compSettings
+		if !entry.orphan {
+			validatedComponentOrder = append(validatedComponentOrder, entry.id)
 		}
+	}
 
-		// blocks
-		validatedBlocks := make(map[string]blocks.Settings, len(compSettings.Blocks))
-		validatedBlockOrder := make([]string, 0, len(compSettings.BlockOrder))
+	themeSettings := t.themeSettings
+	if t.themeSchema != nil {
+		var err error
+		themeSettings, err = ValidateThemeSettings(t.themeSchema, t.themeSettings, handlers...)
+		if err != nil {
+			return err
+		}
+	}
 
-		blockLimit := compSchema.MaxBlocks
-		currBlockCount := uint8(0)
+	slots, err := t.validateSlots(v, validatedComponentOrder, validatedComponents)
+	if err != nil {
+		return err
+	}
+	if err := t.checkPageType(slots, validatedComponentOrder, validatedComponents); err != nil {
+		return err
+	}
 
-		blockSchemaMap := make(map[string]*blocks.Schema, len(compSchema.Blocks))
-		blockTypeCounter := make(map[string]uint8, len(compSchema.Blocks))
-		// build mapping between block type and block schema
-		for _, blockSchema := range compSchema.Blocks {
-			blockSchemaMap[blockSchema.Type] = &blockSchema
-		}
+	if err := v.integrity.err(); err != nil {
+		return err
+	}
 
-		for _, blockID := range compSettings.BlockOrder {
-			if blockLimit != nil && currBlockCount >= *blockLimit {
-				return fmt.Errorf("component %s exceeds max block limit of %d", compID, *blockLimit)
-			}
-			blockSettings, ok := compSettings.Blocks[blockID]
-			if !ok {
-				continue
-			}
-			blockType := blockSettings.Type
-			blockSchema, ok := blockSchemaMap[blockType]
-			if !ok {
-				return fmt.Errorf("schema for block type %s not found in component %s", blockType, compID)
-			}
+	// t only changes once every check passed
+	t.Components = validatedComponents
+	t.Order = validatedComponentOrder
+	t.Slots = slots
+	t.themeSettings = themeSettings
+	t.migrationChanges = v.migrations
+	return nil
+}
 
-			// enforce block type limit
-			if blockSchema.Limit != nil {
-				currCount, ok := blockTypeCounter[blockType]
-				if ok && currCount >= *blockSchema.Limit {
-					return fmt.Errorf("component %s exceeds block type %s limit of %d", compID, blockType, *blockSchema.Limit)
-				}
-			}
+// MigrationChanges returns the changes made by the last Validate migrating
+// components stored with an older schema version
+func (t *JSONTemplate) MigrationChanges() []component.MigrationChange {
+	return t.migrationChanges
+}
 
-			// handle element settings of blocks
-			for _, ele := range blockSchema.Elements {
-				eleVal := blockSettings.GetSettingByID(ele.GetID())
-				if eleVal == nil {
-					continue
-				}
-				var err error
-				for _, handler := range handlers {
-					var handledEleVal jsonx.JSONValue
-					handledEleVal, err = handler.Handle(ele, *eleVal, err)
-					*eleVal = handledEleVal
-				}
-				if err != nil {
-					return fmt.Errorf("component %s block %s element %s value handling failed: %w", compID, blockID, ele.GetID(), err)
-				}
-			}
+// validateComponent validates the settings of a single component against its schema
+func validateComponent(
+	schemaProvider componentschema.ComponentSchemaProvider,
+	v *validation,
+	compID string,
+	compSettings component.Settings,
+	handlers []ElementValueHandler,
+) (component.Settings, error) {
+	compSchema, err := schemaProvider.Get(compSettings.Name)
+	if err != nil {
+		return compSettings, fmt.Errorf("failed to get schema for component %s: %w", compSettings.Name, err)
+	}
+	// migrate settings stored with an older schema before checking them
+	compSettings, changes, err := compSchema.Migrate(compID, compSettings)
+	if err != nil {
+		return compSettings, err
+	}
+	v.migrations = append(v.migrations, changes...)
+
+	// handle element settings of component
+	compSettings.ElementSettings, err = validateElementSettings(
+		fmt.Sprintf("component %s", compID), compSchema.Elements, compSchema.Visibility(),
+		compSettings.ElementSettings, v.mode, handlers,
+	)
+	if err != nil {
+		return compSettings, err
+	}
+	err = compSchema.RuleChecker().Check(fmt.Sprintf("component %s", compID), compSettings.ElementSettings)
+	if err != nil {
+		return compSettings, err
+	}
+
+	// blocks
+	blockOrder, blockSettings, err := validateBlocks(
+		v, fmt.Sprintf("component %s", compID), 1,
+		compSchema.MaxBlocks, compSchema.Blocks,
+		compSettings.BlockOrder, compSettings.Blocks,
+		handlers,
+	)
+	if err != nil {
+		return compSettings, err
+	}
+	compSettings.Blocks = blockSettings
+	compSettings.BlockOrder = blockOrder
+
+	return compSettings, nil
+}
 
-			validatedBlocks[blockID] = blockSettings
+// validateBlocks validates the blocks of a component or of a parent block at
+// depth, scope names the chain of parents for error messages. A nil order or
+// map stays nil when nothing is kept.
+func validateBlocks(
+	v *validation,
+	scope string,
+	depth int,
+	blockLimit *uint8,
+	blockSchemas []blocks.Schema,
+	blockOrder []string,
+	blockSettingsMap map[string]blocks.Settings,
+	handlers []ElementValueHandler,
+) ([]string, map[string]blocks.Settings, error) {
+	blockIDs := make(map[string]string, len(blockSettingsMap))
+	for blockID, blockSettings := range blockSettingsMap {
+		blockIDs[blockID] = blockSettings.ID
+	}
+	plan := v.integrity.plan(scope, blockOrder, blockIDs)
+	// kept orphans are nested just as deep as ordered blocks
+	if (len(blockOrder) > 0 || len(plan) > 0) && depth > blocks.MaxDepth {
+		return nil, nil, fmt.Errorf("%s exceeds max block depth of %d", scope, blocks.MaxDepth)
+	}
+
+	validatedBlocks := make(map[string]blocks.Settings, len(blockSettingsMap))
+	validatedBlockOrder := make([]string, 0, len(blockOrder))
+
+	currBlockCount := uint8(0)
+
+	blockSchemaMap := make(map[string]*blocks.Schema, len(blockSchemas))
+	blockTypeCounter := make(map[string]uint8, len(blockSchemas))
+	// build mapping between block type and block schema
+	for _, blockSchema := range blockSchemas {
+		blockSchemaMap[blockSchema.Type] = &blockSchema
+	}
+
+	for _, entry := range plan {
+		blockID := entry.id
+		if entry.ghost {
 			validatedBlockOrder = append(validatedBlockOrder, blockID)
+			continue
+		}
+		blockSettings := blockSettingsMap[blockID]
+		if entry.fixID {
+			blockSettings.ID = blockID
+		}
+		// orphans are not rendered so they do not count towards the limits
+		counted := !blockSettings.Disabled && !entry.orphan
+		if counted && blockLimit != nil && currBlockCount >= *blockLimit {
+			return nil, nil, fmt.Errorf("%s exceeds max block limit of %d", scope, *blockLimit)
+		}
+		blockType := blockSettings.Type
+		blockSchema, ok := blockSchemaMap[blockType]
+		if !ok {
+			return nil, nil, fmt.Errorf("schema for block type %s not found in %s", blockType, scope)
+		}
 
-			currBlockCount++
-			blockTypeCounter[blockType] = blockTypeCounter[blockType] + 1
+		// enforce block type limit
+		if counted && blockSchema.Limit != nil {
+			currCount, ok := blockTypeCounter[blockType]
+			if ok && currCount >= *blockSchema.Limit {
+				return nil, nil, fmt.Errorf("%s exceeds block type %s limit of %d", scope, blockType, *blockSchema.Limit)
+			}
+		}
 
-			compSettings.Blocks = validatedBlocks
-			compSettings.BlockOrder = validatedBlockOrder
+		// handle element settings of blocks
+		elementSettings, err := validateElementSettings(
+			fmt.Sprintf("%s block %s", scope, blockID), blockSchema.Elements, blockSchema.Visibility(),
+			blockSettings.ElementSettings, v.mode, handlers,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		blockSettings.ElementSettings = elementSettings
+		err = blockSchema.RuleChecker().Check(fmt.Sprintf("%s block %s", scope, blockID), elementSettings)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		validatedComponents[compID] = compSettings
-		validatedComponentOrder = append(validatedComponentOrder, compID)
-	}
+		// handle child blocks
+		childOrder, childSettings, err := validateBlocks(
+			v, fmt.Sprintf("%s block %s", scope, blockID), depth+1,
+			blockSchema.MaxBlocks, blockSchema.Blocks,
+			blockSettings.BlockOrder, blockSettings.Blocks,
+			handlers,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		blockSettings.Blocks = childSettings
+		blockSettings.BlockOrder = childOrder
 
-	t.Components = validatedComponents
-	t.Order = validatedComponentOrder
+		validatedBlocks[blockID] = blockSettings
+		if !entry.orphan {
+			validatedBlockOrder = append(validatedBlockOrder, blockID)
+		}
 
-	return nil
+		if !counted {
+			continue
+		}
+		currBlockCount++
+		blockTypeCounter[blockType] = blockTypeCounter[blockType] + 1
+	}
+
+	if blockOrder == nil && len(validatedBlockOrder) == 0 {
+		validatedBlockOrder = nil
+	}
+	if blockSettingsMap == nil && len(validatedBlocks) == 0 {
+		validatedBlocks = nil
+	}
+	return validatedBlockOrder, validatedBlocks, nil
 }
 
-func (t *JSONTemplate) ToProps() (map[string]any, error) {
+func (t *JSONTemplate) ToProps(localeCode string) (map[string]any, error) {
 	if t.schemaProvider == nil {
 		return nil, fmt.Errorf("schema provider is nil")
 	}
 
-	props := make(map[string]any)
-	for _, compID := range t.Order {
-		compSettings, ok := t.Components[compID]
-		if !ok {
+	formatter := locale.NewFormatter(localeCode)
+	localeProps := formatter.ToLiquid()
+
+	themeProps, err := t.themeSettingsToProps()
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := t.renderSections()
+	if err != nil {
+		return nil, err
+	}
+
+	// global settings sit next to the components, order lists the rendered
+	// components of the page and of its section groups
+	props := map[string]any{"settings": themeProps}
+	order := make([]string, 0, len(t.Order))
+	for _, section := range sections {
+		for _, compID := range section.order {
+			if slices.Contains(reservedComponentIDs, compID) {
+				return nil, fmt.Errorf("%w: component %s", ErrReservedComponentID, compID)
+			}
+			// duplicate order entries kept by IntegrityKeep are rendered once
+			if _, ok := props[compID]; ok {
+				continue
+			}
+			compSettings, ok := section.tpl.Components[compID]
+			if !ok || compSettings.Disabled {
+				continue
+			}
+			compProps, err := componentToProps(section.tpl.schemaProvider, compID, compSettings, formatter)
+			if err != nil {
+				return nil, err
+			}
+			compProps["locale"] = localeProps
+
+			props[compID] = compProps
+			order = append(order, compID)
+		}
+	}
+	props["order"] = order
+
+	return props, nil
+}
+
+// componentToProps converts the settings of a single component to liquid props
+func componentToProps(
+	schemaProvider componentschema.ComponentSchemaProvider,
+	compID string,
+	compSettings component.Settings,
+	formatter *locale.Formatter,
+) (map[string]any, error) {
+	schema, err := schemaProvider.Get(compSettings.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema for component %s: %w", compSettings.Name, err)
+	}
+
+	compElementProps := make(map[string]any)
+	compFormattedProps := make(map[string]any)
+	for _, ele := range schema.Elements {
+		eleVal := compSettings.GetElementSettingByID(ele.GetID())
+		// hidden values are kept unchecked by Validate, they are not rendered
+		if eleVal == nil || !schema.Visibility().IsVisible(ele.GetID(), compSettings.ElementSettings) {
 			continue
 		}
-		schema, err := t.schemaProvider.Get(compSettings.Name)
+		liquidVal, err := ele.ToLiquid(*eleVal)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get schema for component %s: %w", compSettings.Name, err)
+			return nil, fmt.Errorf("component %s element %s to liquid failed: %w", compID, ele.GetID(), err)
 		}
+		compElementProps[ele.GetID()] = liquidVal
 
-		compElementProps := make(map[string]any)
-		for _, ele := range schema.Elements {
-			eleVal := compSettings.GetElementSettingByID(ele.GetID())
-			if eleVal == nil {
-				continue
-			}
-			liquidVal, err := ele.ToLiquid(*eleVal)
+		if valFormatter, ok := ele.(element.ValueFormatter); ok {
+			formattedVal, err := valFormatter.FormatValue(*eleVal, formatter)
 			if err != nil {
-				return nil, fmt.Errorf("component %s element %s to liquid failed: %w", compID, ele.GetID(), err)
+				return nil, fmt.Errorf("component %s element %s formatting failed: %w", compID, ele.GetID(), err)
 			}
-			compElementProps[ele.GetID()] = liquidVal
+			compFormattedProps[ele.GetID()] = formattedVal
 		}
+	}
+
+	// handle blocks
+	blockPropsSlice, err := blocksToProps(
+		fmt.Sprintf("component %s", compID), 1,
+		schema.Blocks, compSettings.BlockOrder, compSettings.Blocks,
+		formatter,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	compProps := make(map[string]any)
+	compProps["id"] = compID
+	compProps["settings"] = compElementProps
+	compProps["formatted_settings"] = compFormattedProps
+	compProps["blocks"] = blockPropsSlice
+
+	return compProps, nil
+}
 
-		// handle blocks
-		blockPropsSlice := make([]map[string]any, 0, len(compSettings.Blocks))
-		blockSchemaMap := make(map[string]*blocks.Schema, len(schema.Blocks))
-		// build mapping between block type and block schema
-		for _, blockSchema := range schema.Blocks {
-			blockSchemaMap[blockSchema.Type] = &blockSchema
+// blocksToProps converts the blocks of a component or of a parent block at
+// depth to liquid props, child blocks are nested under blocks
+func blocksToProps(
+	scope string,
+	depth int,
+	blockSchemas []blocks.Schema,
+	blockOrder []string,
+	blockSettingsMap map[string]blocks.Settings,
+	formatter *locale.Formatter,
+) ([]map[string]any, error) {
+	if len(blockOrder) > 0 && depth > blocks.MaxDepth {
+		return nil, fmt.Errorf("%s exceeds max block depth of %d", scope, blocks.MaxDepth)
+	}
+
+	blockPropsSlice := make([]map[string]any, 0, len(blockSettingsMap))
+	blockSchemaMap := make(map[string]*blocks.Schema, len(blockSchemas))
+	// build mapping between block type and block schema
+	for _, blockSchema := range blockSchemas {
+		blockSchemaMap[blockSchema.Type] = &blockSchema
+	}
+	rendered := make(map[string]struct{}, len(blockOrder))
+	for _, blockID := range blockOrder {
+		if _, ok := rendered[blockID]; ok {
+			continue
+		}
+		rendered[blockID] = struct{}{}
+		blockSettings, ok := blockSettingsMap[blockID]
+		if !ok || blockSettings.Disabled {
+			continue
+		}
+		blockSchema, ok := blockSchemaMap[blockSettings.Type]
+		if !ok {
+			return nil, fmt.Errorf("schema for block type %s not found in %s", blockSettings.Type, scope)
 		}
-		for _, blockID := range compSettings.BlockOrder {
-			blockSettings, ok := compSettings.Blocks[blockID]
-			if !ok {
+
+		blockProps := make(map[string]any)
+		blockElements := make(map[string]any)
+		blockFormattedElements := make(map[string]any)
+		for _, ele := range blockSchema.Elements {
+			eleVal := blockSettings.GetSettingByID(ele.GetID())
+			if eleVal == nil || !blockSchema.Visibility().IsVisible(ele.GetID(), blockSettings.ElementSettings) {
 				continue
 			}
-			blockSchema, ok := blockSchemaMap[blockSettings.Type]
-			if !ok {
-				return nil, fmt.Errorf("schema for block type %s not found in component %s", blockSettings.Type, compID)
+			liquidVal, err := ele.ToLiquid(*eleVal)
+			if err != nil {
+				return nil, fmt.Errorf("%s block %s element %s to liquid failed: %w", scope, blockID, ele.GetID(), err)
 			}
+			blockElements[ele.GetID()] = liquidVal
 
-			blockProps := make(map[string]any)
-			blockElements := make(map[string]any)
-			for _, ele := range blockSchema.Elements {
-				eleVal := blockSettings.GetSettingByID(ele.GetID())
-				if eleVal == nil {
-					continue
-				}
-				liquidVal, err := ele.ToLiquid(*eleVal)
+			if valFormatter, ok := ele.(element.ValueFormatter); ok {
+				formattedVal, err := valFormatter.FormatValue(*eleVal, formatter)
 				if err != nil {
-					return nil, fmt.Errorf("component %s block %s element %s to liquid failed: %w", compID, blockID, ele.GetID(), err)
+					return nil, fmt.Errorf("%s block %s element %s formatting failed: %w", scope, blockID, ele.GetID(), err)
 				}
-				blockElements[ele.GetID()] = liquidVal
+				blockFormattedElements[ele.GetID()] = formattedVal
 			}
-			blockProps["id"] = blockSettings.Type
-			blockProps["settings"] = blockElements
+		}
 
-			blockPropsSlice = append(blockPropsSlice, blockProps)
+		childPropsSlice, err := blocksToProps(
+			fmt.Sprintf("%s block %s", scope, blockID), depth+1,
+			blockSchema.Blocks, blockSettings.BlockOrder, blockSettings.Blocks,
+			formatter,
+		)
+		if err != nil {
+			return nil, err
 		}
 
-		compProps := make(map[string]any)
-		compProps["id"] = compID
-		compProps["settings"] = compElementProps
-		compProps["blocks"] = blockPropsSlice
+		blockProps["id"] = blockSettings.Type
+		blockProps["settings"] = blockElements
+		blockProps["formatted_settings"] = blockFormattedElements
+		blockProps["blocks"] = childPropsSlice
 
-		props[compID] = compProps
+		blockPropsSlice = append(blockPropsSlice, blockProps)
 	}
-
-	return props, nil
+	return blockPropsSlice, nil
 }
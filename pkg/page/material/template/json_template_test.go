@@ -0,0 +1,509 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+	"github.com/osteele/liquid/values"
+)
+
+// pageSchemasRaw are a title component with a title and a sub title block and
+// a text component, shared by the template tests
+var pageSchemasRaw = map[string]string{
+	"title": `{
+		"name": "title",
+		"max_blocks": 2,
+		"elements": [
+			{"type": "range", "id": "padding_top", "label": "Top", "min": 0, "max": 100, "unit": "px", "default": 36},
+			{"type": "range", "id": "padding_bottom", "label": "Bottom", "min": 0, "max": 100, "unit": "px", "default": 36}
+		],
+		"blocks": [
+			{"type": "title", "name": "Title", "limit": 1, "elements": [
+				{"type": "text", "id": "title_text", "label": "Text", "default": "Title"},
+				{"type": "select", "id": "title_size", "label": "Size", "default": "h1", "options": [
+					{"value": "h1", "label": "H1"}, {"value": "h2", "label": "H2"}
+				]}
+			]},
+			{"type": "sub_title", "name": "Sub title", "limit": 1, "elements": [
+				{"type": "text", "id": "sub_title_text", "label": "Text", "default": "Sub title"},
+				{"type": "range", "id": "sub_title_opacity", "label": "Opacity", "min": 0, "max": 100, "default": 100}
+			]}
+		]
+	}`,
+	"text": `{
+		"name": "text",
+		"elements": [{"type": "text", "id": "content", "label": "Content", "default": "Text"}],
+		"blocks": [{"type": "line", "name": "Line", "elements": [{"type": "text", "id": "line_text", "label": "Text", "default": ""}]}]
+	}`,
+}
+
+// pageRaw is a page with a title and a text component
+var pageRaw = []byte(`{
+	"name": "page",
+	"order": ["comp_title", "comp_text"],
+	"components": {
+		"comp_title": {
+			"id": "comp_title",
+			"name": "title",
+			"element_settings": {"padding_top": 36, "padding_bottom": 36},
+			"block_order": ["blc_title", "blc_sub_title"],
+			"blocks": {
+				"blc_title": {"id": "blc_title", "type": "title", "element_settings": {"title_text": "Hello", "title_size": "h1"}},
+				"blc_sub_title": {"id": "blc_sub_title", "type": "sub_title", "element_settings": {"sub_title_text": "World", "sub_title_opacity": 100}}
+			}
+		},
+		"comp_text": {
+			"id": "comp_text",
+			"name": "text",
+			"element_settings": {"content": "Text"},
+			"block_order": ["blc_line"],
+			"blocks": {"blc_line": {"id": "blc_line", "type": "line", "element_settings": {"line_text": "Line"}}}
+		}
+	}
+}`)
+
+// parseSchema parses a raw component schema
+func parseSchema(t *testing.T, raw []byte) *component.Schema {
+	t.Helper()
+	var rawSchema jsonmodel.ComponentSchema
+	if err := json.Unmarshal(raw, &rawSchema); err != nil {
+		t.Fatalf("failed to unmarshal component schema: %v", err)
+	}
+	schema, err := component.Parse(rawSchema)
+	if err != nil {
+		t.Fatalf("failed to parse component schema: %v", err)
+	}
+	return schema
+}
+
+// pageSchemas parses pageSchemasRaw, raw schemas in overrides are added or
+// replace them by component name
+func pageSchemas(t *testing.T, overrides map[string][]byte) componentschema.ComponentSchemaProvider {
+	t.Helper()
+	raws := make(map[string][]byte, len(pageSchemasRaw)+len(overrides))
+	for name, raw := range pageSchemasRaw {
+		raws[name] = []byte(raw)
+	}
+	for name, raw := range overrides {
+		raws[name] = raw
+	}
+	schemas := make(map[string]component.Schema, len(raws))
+	for name, raw := range raws {
+		schemas[name] = *parseSchema(t, raw)
+	}
+	return componentschema.NewInMemorySchemaProvider(schemas)
+}
+
+// mutation sets value at path of a raw json fixture
+type mutation struct {
+	path  string
+	value any
+}
+
+// mutate returns a copy of raw with the mutations applied in order
+func mutate(t *testing.T, raw []byte, mutations ...mutation) []byte {
+	t.Helper()
+	val := jsonx.JSONValue{RawMessage: append([]byte(nil), raw...)}
+	for _, m := range mutations {
+		if err := val.Set(m.path, m.value); err != nil {
+			t.Fatalf("failed to set %s: %v", m.path, err)
+		}
+	}
+	return val.RawMessage
+}
+
+// parsePage parses raw without validating it
+func parsePage(t *testing.T, raw []byte, schemaProvider componentschema.ComponentSchemaProvider) *JSONTemplate {
+	t.Helper()
+	tpl, err := ParseJSON(raw, schemaProvider)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	return tpl
+}
+
+func TestValidateUnparsedSchema(t *testing.T) {
+	// schemas built without component.Parse have no visible_if and no rules
+	schemaProvider := componentschema.NewInMemorySchemaProvider(map[string]component.Schema{"hero": {}})
+	tpl, err := ParseJSON([]byte(`{
+		"name": "page",
+		"order": ["comp_hero"],
+		"components": {"comp_hero": {"id": "comp_hero", "name": "hero", "element_settings": {"title": "Hero"}}}
+	}`), schemaProvider)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := tpl.ToProps("en-US"); err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+
+	schema := component.Schema{}
+	if !schema.IsElementVisible("title", tpl.Components["comp_hero"]) {
+		t.Fatal("expected every element of an unparsed schema to be visible")
+	}
+}
+
+func TestValidateKeepsIssuesOnFailure(t *testing.T) {
+	schemaProvider := componentschema.NewInMemorySchemaProvider(map[string]component.Schema{"hero": {}})
+	tpl, err := ParseJSON([]byte(`{
+		"name": "page",
+		"order": ["comp_hero", "comp_missing"],
+		"components": {
+			"comp_hero": {"id": "comp_hero", "name": "hero"},
+			"comp_orphan": {"id": "comp_orphan", "name": "hero"}
+		}
+	}`), schemaProvider)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	if err := tpl.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tpl.IntegrityIssues()) != 2 {
+		t.Fatalf("expected 2 issues, got %v", tpl.IntegrityIssues())
+	}
+
+	// a failing run replaces the issues of the previous one
+	tpl.Components["comp_broken"] = component.Settings{ID: "comp_broken", Name: "unknown"}
+	tpl.Order = append(tpl.Order, "comp_broken", "comp_broken")
+	if err := tpl.Validate(); err == nil {
+		t.Fatal("expected error for unknown component schema")
+	}
+	issues := make([]string, 0, len(tpl.IntegrityIssues()))
+	for _, issue := range tpl.IntegrityIssues() {
+		issues = append(issues, issue.String())
+	}
+	if strings.Join(issues, ",") != "template: duplicate comp_broken" {
+		t.Fatalf("expected issues of the failed run, got %v", issues)
+	}
+}
+
+func TestValidateReservedComponentIDs(t *testing.T) {
+	schemaProvider := componentschema.NewInMemorySchemaProvider(map[string]component.Schema{"hero": {}})
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{
+			name: "component",
+			raw:  `{"name": "page", "order": ["settings"], "components": {"settings": {"id": "settings", "name": "hero"}}}`,
+		},
+		{
+			// an orphan would still collide with the props
+			name: "orphan",
+			raw:  `{"name": "page", "order": [], "components": {"order": {"id": "order", "name": "hero"}}}`,
+		},
+		{
+			name: "dangling order entry",
+			raw:  `{"name": "page", "order": ["settings"], "components": {}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := ParseJSON([]byte(tt.raw), schemaProvider)
+			if err != nil {
+				t.Fatalf("failed to parse template: %v", err)
+			}
+			if err := tpl.Validate(); !errors.Is(err, ErrReservedComponentID) {
+				t.Fatalf("expected error %v, got %v", ErrReservedComponentID, err)
+			}
+		})
+	}
+
+	groups := map[string]*JSONTemplate{
+		"header": {Name: "header", Components: map[string]component.Settings{"settings": {ID: "settings"}}},
+	}
+	if _, err := ParseLayout([]byte(`{"name": "default", "sections": [{"group": "header"}]}`), groups); !errors.Is(err, ErrReservedComponentID) {
+		t.Fatalf("expected error %v for a group, got %v", ErrReservedComponentID, err)
+	}
+}
+
+func TestNestedBlocks(t *testing.T) {
+	schemaProvider := pageSchemas(t, map[string][]byte{"layout": []byte(`{
+		"name": "layout",
+		"elements": [],
+		"blocks": [{"type": "row", "name": "Row", "max_blocks": 2, "blocks": [
+			{"type": "column", "name": "Column", "elements": [
+				{"type": "range", "id": "column_span", "label": "Span", "min": 1, "max": 12, "default": 6}
+			], "blocks": [
+				{"type": "content", "name": "Content", "limit": 1, "elements": [
+					{"type": "text", "id": "content_text", "label": "Content", "default": ""}
+				]}
+			]}
+		]}]
+	}`)})
+
+	column := func(id, childType string) string {
+		return `"` + id + `": {"id": "` + id + `", "type": "column", "element_settings": {"column_span": 4},
+			"block_order": ["blc_content"],
+			"blocks": {"blc_content": {"id": "blc_content", "type": "` + childType + `", "element_settings": {"content_text": "` + id + `"}}}}`
+	}
+	layoutPage := func(columnIDs []string, childType string) []byte {
+		columns := make([]string, 0, len(columnIDs))
+		for _, id := range columnIDs {
+			columns = append(columns, column(id, childType))
+		}
+		order, _ := json.Marshal(columnIDs)
+		return []byte(`{"name": "layout_page", "order": ["comp_layout"], "components": {"comp_layout": {
+			"id": "comp_layout", "name": "layout", "element_settings": {},
+			"block_order": ["blc_row"],
+			"blocks": {"blc_row": {"id": "blc_row", "type": "row", "block_order": ` + string(order) + `,
+				"blocks": {` + strings.Join(columns, ",") + `}}}}}}`)
+	}
+
+	tpl := parsePage(t, layoutPage([]string{"blc_col_1", "blc_col_2"}, "content"), schemaProvider)
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+	row := props["comp_layout"].(map[string]any)["blocks"].([]map[string]any)[0]
+	columns := row["blocks"].([]map[string]any)
+	if len(columns) != 2 {
+		t.Fatalf("expected 2 columns, got %d", len(columns))
+	}
+	content := columns[1]["blocks"].([]map[string]any)[0]
+	if text := content["settings"].(map[string]any)["content_text"].(values.Value).Interface(); text != "blc_col_2" {
+		t.Fatalf("expected content of second column, got %v", text)
+	}
+
+	errTests := []struct {
+		name     string
+		raw      []byte
+		expected string
+	}{
+		{
+			name:     "per parent limit",
+			raw:      layoutPage([]string{"blc_col_1", "blc_col_2", "blc_col_3"}, "content"),
+			expected: "component comp_layout block blc_row exceeds max block limit of 2",
+		},
+		{
+			name:     "child type not allowed",
+			raw:      layoutPage([]string{"blc_col_1"}, "row"),
+			expected: "schema for block type row not found in component comp_layout block blc_row block blc_col_1",
+		},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parsePage(t, tt.raw, schemaProvider).Validate(NewElementValueChecker())
+			if err == nil || err.Error() != tt.expected {
+				t.Fatalf("expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateInstantiatedPreset(t *testing.T) {
+	titleSchemaRaw := mutate(t, []byte(pageSchemasRaw["title"]), mutation{path: "presets", value: json.RawMessage(`[{
+		"id": "default",
+		"name": "Default",
+		"element_settings": {"padding_top": 24},
+		"blocks": [{"type": "title", "element_settings": {"title_size": "h2"}}, {"type": "sub_title"}]
+	}]`)})
+	schemaProvider := pageSchemas(t, map[string][]byte{"title": titleSchemaRaw})
+	schema, err := schemaProvider.Get("title")
+	if err != nil {
+		t.Fatalf("failed to get schema: %v", err)
+	}
+	preset, ok := schema.GetPreset("default")
+	if !ok {
+		t.Fatal("expected preset default")
+	}
+	compSettings, err := preset.Instantiate("comp_title_2", "title")
+	if err != nil {
+		t.Fatalf("failed to instantiate preset: %v", err)
+	}
+
+	tpl := parsePage(t, []byte(`{"name": "page", "order": [], "components": {}}`), schemaProvider)
+	tpl.Components["comp_title_2"] = compSettings
+	tpl.Order = append(tpl.Order, "comp_title_2")
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("expected instantiated preset to be valid: %v", err)
+	}
+	if len(tpl.MigrationChanges()) != 0 {
+		t.Fatalf("expected instantiated preset not to be migrated, got %v", tpl.MigrationChanges())
+	}
+}
+
+func TestDisabledComponentsAndBlocks(t *testing.T) {
+	// a second title would exceed both the title limit and max blocks if it was enabled
+	page := mutate(t, pageRaw,
+		mutation{path: "components.comp_title.blocks.blc_title_old", value: map[string]any{
+			"id": "blc_title_old", "type": "title", "disabled": true,
+			"element_settings": map[string]any{"title_text": "Old title", "title_size": "h2"},
+		}},
+		mutation{path: "components.comp_title.block_order.-1", value: "blc_title_old"},
+		mutation{path: "components.comp_text.disabled", value: true},
+	)
+	tpl := parsePage(t, page, pageSchemas(t, nil))
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("expected disabled block not to count towards limits: %v", err)
+	}
+	if _, ok := tpl.Components["comp_title"].Blocks["blc_title_old"]; !ok {
+		t.Fatal("expected disabled block to be kept")
+	}
+	if !tpl.Components["comp_text"].Disabled {
+		t.Fatal("expected disabled component to be kept")
+	}
+
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+	if _, ok := props["comp_text"]; ok {
+		t.Fatal("expected disabled component not to be rendered")
+	}
+	if order := props["order"].([]string); strings.Join(order, ",") != "comp_title" {
+		t.Fatalf("expected disabled component not to be in the render order, got %v", order)
+	}
+	blockProps := props["comp_title"].(map[string]any)["blocks"].([]map[string]any)
+	if len(blockProps) != 2 {
+		t.Fatalf("expected disabled block not to be rendered, got %d blocks", len(blockProps))
+	}
+}
+
+func TestValidateMigrations(t *testing.T) {
+	titleSchemaRaw := mutate(t, []byte(pageSchemasRaw["title"]),
+		mutation{path: "version", value: 2},
+		mutation{path: "migrations", value: json.RawMessage(`[
+			{"version": 1, "steps": [
+				{"op": "rename_element", "element": "padding_top_old", "to": "padding_top"},
+				{"op": "clamp_range", "element": "padding_bottom", "min": 0, "max": 100}
+			]},
+			{"version": 2, "steps": [
+				{"op": "split_block_type", "block_type": "heading", "element": "level", "cases": {"2": "sub_title"}, "to": "title"},
+				{"op": "map_select_values", "block_type": "title", "element": "title_size", "values": {"large": "h2"}}
+			]}
+		]`)},
+	)
+	schemaProvider := pageSchemas(t, map[string][]byte{"title": titleSchemaRaw})
+
+	tpl := parsePage(t, []byte(`{
+		"name": "page",
+		"components": {
+			"comp_title": {
+				"id": "comp_title",
+				"name": "title",
+				"element_settings": {"padding_top_old": 20, "padding_bottom": 150},
+				"block_order": ["blc_a", "blc_b"],
+				"blocks": {
+					"blc_a": {"id": "blc_a", "type": "heading", "element_settings": {"level": "2", "sub_title_text": "Sub title"}},
+					"blc_b": {"id": "blc_b", "type": "heading", "element_settings": {"level": "1", "title_text": "Title", "title_size": "large"}}
+				}
+			}
+		},
+		"order": ["comp_title"]
+	}`), schemaProvider)
+	tpl.SetUnknownSettingsPolicy(UnknownSettingsPrune)
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("expected migrated template to validate: %v", err)
+	}
+
+	compSettings := tpl.Components["comp_title"]
+	if compSettings.SchemaVersion != 2 {
+		t.Fatalf("expected schema version 2, got %d", compSettings.SchemaVersion)
+	}
+	if got := compSettings.ElementSettings["padding_top"].String(); got != "20" {
+		t.Fatalf("expected renamed padding_top 20, got %s", got)
+	}
+	if got := compSettings.ElementSettings["padding_bottom"].String(); got != "100" {
+		t.Fatalf("expected padding_bottom clamped to 100, got %s", got)
+	}
+	if got := compSettings.Blocks["blc_a"].Type; got != "sub_title" {
+		t.Fatalf("expected blc_a split to sub_title, got %s", got)
+	}
+	if got := compSettings.Blocks["blc_b"].ElementSettings["title_size"].String(); got != "h2" {
+		t.Fatalf("expected title_size mapped to h2, got %s", got)
+	}
+
+	expectedChanges := []string{
+		"v1 component comp_title: renamed element padding_top_old to padding_top",
+		"v1 component comp_title: clamped element padding_bottom value 150 to 100",
+		"v2 component comp_title block blc_a: split block type heading to sub_title",
+		"v2 component comp_title block blc_b: split block type heading to title",
+		`v2 component comp_title block blc_b: mapped element title_size value "large" to "h2"`,
+	}
+	changes := make([]string, 0, len(tpl.MigrationChanges()))
+	for _, change := range tpl.MigrationChanges() {
+		changes = append(changes, change.String())
+	}
+	if strings.Join(changes, "\n") != strings.Join(expectedChanges, "\n") {
+		t.Fatalf("expected changes %v, got %v", expectedChanges, changes)
+	}
+
+	// validating again is a no-op as the settings are at the schema version
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tpl.MigrationChanges()) != 0 {
+		t.Fatalf("expected no changes, got %v", tpl.MigrationChanges())
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	titleSchemaRaw := mutate(t, []byte(pageSchemasRaw["title"]),
+		mutation{path: "rules", value: []map[string]string{{
+			"id": "padding_sum", "expr": "padding_top + padding_bottom <= 120", "message": "Paddings are too large",
+		}}},
+		mutation{path: "blocks.1.rules", value: []map[string]string{{
+			"id": "visible_sub_title", "expr": `sub_title_text == "" || sub_title_opacity >= 50`, "message": "Sub title must be at least half opaque",
+		}}},
+	)
+	schemaProvider := pageSchemas(t, map[string][]byte{"title": titleSchemaRaw})
+
+	tests := []struct {
+		name            string
+		paddingTop      int
+		paddingBottom   int
+		subTitleOpacity int
+		wantPath        string
+		wantRule        string
+	}{
+		{name: "rules hold", paddingTop: 60, paddingBottom: 60, subTitleOpacity: 50},
+		{
+			name: "component rule", paddingTop: 100, paddingBottom: 50, subTitleOpacity: 100,
+			wantPath: "component comp_title", wantRule: "padding_sum",
+		},
+		{
+			name: "block rule", paddingTop: 36, paddingBottom: 36, subTitleOpacity: 20,
+			wantPath: "component comp_title block blc_sub_title", wantRule: "visible_sub_title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := mutate(t, pageRaw,
+				mutation{path: "components.comp_title.element_settings.padding_top", value: tt.paddingTop},
+				mutation{path: "components.comp_title.element_settings.padding_bottom", value: tt.paddingBottom},
+				mutation{path: "components.comp_title.blocks.blc_sub_title.element_settings.sub_title_opacity", value: tt.subTitleOpacity},
+			)
+			err := parsePage(t, page, schemaProvider).Validate(NewElementValueChecker())
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var violation *element.RuleViolation
+			if !errors.As(err, &violation) {
+				t.Fatalf("expected rule violation, got %v", err)
+			}
+			if violation.Path != tt.wantPath || violation.RuleID != tt.wantRule {
+				t.Fatalf("expected %s rule %s, got %s rule %s", tt.wantPath, tt.wantRule, violation.Path, violation.RuleID)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+)
+
+// DefaultSlot receives the components of a page that are not assigned to a slot
+const DefaultSlot = "main"
+
+// ErrReservedComponentID is returned for component ids colliding with the top
+// level props ToProps outputs next to the components
+var ErrReservedComponentID = errors.New("reserved component id")
+
+// reservedComponentIDs are the top level props next to the components
+var reservedComponentIDs = []string{"order", "settings"}
+
+// checkReservedComponentIDs rejects reserved ids among the components and
+// the order entries of a template
+func checkReservedComponentIDs(scope string, components map[string]component.Settings, order []string) error {
+	for _, compID := range reservedComponentIDs {
+		_, ok := components[compID]
+		if ok || slices.Contains(order, compID) {
+			return fmt.Errorf("%w: %s component %s", ErrReservedComponentID, scope, compID)
+		}
+	}
+	return nil
+}
+
+// LayoutSection is either a shared section group or a slot filled by the page
+type LayoutSection struct {
+	Group string `json:"group,omitempty"`
+	Slot  string `json:"slot,omitempty"`
+}
+
+// Layout arranges shared section groups, e.g. the header and the footer, and
+// the slots page templates fill with their own components. Section groups are
+// json templates themselves, they are validated once by the layout instead of
+// once per page.
+type Layout struct {
+	Name     string          `json:"name"`
+	Sections []LayoutSection `json:"sections"`
+
+	groups map[string]*JSONTemplate `json:"-"`
+}
+
+// ParseLayout parses a layout referencing groups by name, component ids have
+// to be unique across the groups
+func ParseLayout(raw json.RawMessage, groups map[string]*JSONTemplate) (*Layout, error) {
+	var l Layout
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal layout: %w", err)
+	}
+
+	l.groups = make(map[string]*JSONTemplate)
+	slots := make(map[string]struct{})
+	compGroups := make(map[string]string)
+	for i, section := range l.Sections {
+		switch {
+		case section.Group != "" && section.Slot != "":
+			return nil, fmt.Errorf("layout %s section %d is both a group and a slot", l.Name, i)
+		case section.Slot != "":
+			if _, ok := slots[section.Slot]; ok {
+				return nil, fmt.Errorf("layout %s declares slot %s twice", l.Name, section.Slot)
+			}
+			slots[section.Slot] = struct{}{}
+		case section.Group != "":
+			if _, ok := l.groups[section.Group]; ok {
+				return nil, fmt.Errorf("layout %s references group %s twice", l.Name, section.Group)
+			}
+			group, ok := groups[section.Group]
+			if !ok || group == nil {
+				return nil, fmt.Errorf("layout %s references unknown group %s", l.Name, section.Group)
+			}
+			if group.Layout != "" {
+				return nil, fmt.Errorf("group %s can not have a layout", section.Group)
+			}
+			if err := checkReservedComponentIDs("group "+section.Group, group.Components, group.Order); err != nil {
+				return nil, err
+			}
+			for compID := range group.Components {
+				if other, ok := compGroups[compID]; ok {
+					return nil, fmt.Errorf("component %s is in both group %s and %s", compID, other, section.Group)
+				}
+				compGroups[compID] = section.Group
+			}
+			l.groups[section.Group] = group
+		default:
+			return nil, fmt.Errorf("layout %s section %d is neither a group nor a slot", l.Name, i)
+		}
+	}
+	return &l, nil
+}
+
+// Validate validates every section group of the layout
+func (l *Layout) Validate(handlers ...ElementValueHandler) error {
+	for _, section := range l.Sections {
+		if section.Group == "" {
+			continue
+		}
+		if err := l.groups[section.Group].Validate(handlers...); err != nil {
+			return fmt.Errorf("group %s: %w", section.Group, err)
+		}
+	}
+	return nil
+}
+
+// Group returns the section group named name
+func (l *Layout) Group(name string) (*JSONTemplate, bool) {
+	group, ok := l.groups[name]
+	return group, ok
+}
+
+func (l *Layout) hasSlot(name string) bool {
+	return slices.ContainsFunc(l.Sections, func(section LayoutSection) bool {
+		return section.Slot == name
+	})
+}
+
+// SetLayout binds the layout named by the Layout field of t, Validate only
+// validates the components of the page, the groups are left to Layout.Validate
+func (t *JSONTemplate) SetLayout(layout *Layout) {
+	t.layout = layout
+}
+
+func (t *JSONTemplate) boundLayout() (*Layout, error) {
+	if t.layout == nil {
+		return nil, fmt.Errorf("layout %s is not bound", t.Layout)
+	}
+	if t.layout.Name != t.Layout {
+		return nil, fmt.Errorf("template uses layout %s, got %s", t.Layout, t.layout.Name)
+	}
+	return t.layout, nil
+}
+
+// validateSlots checks the validated order and components of the page against
+// its layout and returns the slots without the entries missing from the order,
+// according to the integrity policy
+func (t *JSONTemplate) validateSlots(
+	v *validation,
+	order []string,
+	components map[string]component.Settings,
+) (map[string][]string, error) {
+	if t.Layout == "" {
+		if len(t.Slots) > 0 {
+			return nil, fmt.Errorf("template fills slots without a layout")
+		}
+		return t.Slots, nil
+	}
+	layout, err := t.boundLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	for compID := range components {
+		for name, group := range layout.groups {
+			if _, ok := group.Components[compID]; ok {
+				return nil, fmt.Errorf("component %s is already in group %s", compID, name)
+			}
+		}
+	}
+
+	inOrder := make(map[string]struct{}, len(order))
+	for _, compID := range order {
+		inOrder[compID] = struct{}{}
+	}
+	keep := v.integrity.policy == IntegrityKeep
+	assigned := make(map[string]struct{})
+	slotNames := make([]string, 0, len(t.Slots))
+	for name := range t.Slots {
+		slotNames = append(slotNames, name)
+	}
+	slices.Sort(slotNames)
+	slots := make(map[string][]string, len(t.Slots))
+	for _, name := range slotNames {
+		if !layout.hasSlot(name) {
+			return nil, fmt.Errorf("layout %s has no slot %s", layout.Name, name)
+		}
+		scope := fmt.Sprintf("slot %s", name)
+		validated := make([]string, 0, len(t.Slots[name]))
+		for _, compID := range t.Slots[name] {
+			if _, ok := assigned[compID]; ok {
+				v.integrity.report(IntegrityDuplicate, scope, compID)
+				if !keep {
+					continue
+				}
+			} else if _, ok := inOrder[compID]; !ok {
+				v.integrity.report(IntegrityDangling, scope, compID)
+				if !keep {
+					continue
+				}
+			}
+			assigned[compID] = struct{}{}
+			validated = append(validated, compID)
+		}
+		slots[name] = validated
+	}
+
+	for _, compID := range order {
+		if _, ok := assigned[compID]; !ok && !layout.hasSlot(DefaultSlot) {
+			return nil, fmt.Errorf("component %s is not assigned to a slot and layout %s has no %s slot", compID, layout.Name, DefaultSlot)
+		}
+	}
+	if t.Slots == nil && len(slots) == 0 {
+		return nil, nil
+	}
+	return slots, nil
+}
+
+// slotOrder returns the components filling slot name, the default slot also
+// receives the components of the order not assigned to any slot
+func slotOrder(slots map[string][]string, order []string, name string) []string {
+	filled := slices.Clone(slots[name])
+	if name != DefaultSlot {
+		return filled
+	}
+	assigned := make(map[string]struct{})
+	for _, slot := range slots {
+		for _, compID := range slot {
+			assigned[compID] = struct{}{}
+		}
+	}
+	for _, compID := range order {
+		if _, ok := assigned[compID]; !ok {
+			filled = append(filled, compID)
+		}
+	}
+	return filled
+}
+
+// renderSection is an ordered run of components of a page or of a section group
+type renderSection struct {
+	tpl   *JSONTemplate
+	order []string
+}
+
+// renderSections returns the sections of the page in render order
+func (t *JSONTemplate) renderSections() ([]renderSection, error) {
+	if t.Layout == "" {
+		return []renderSection{{tpl: t, order: t.Order}}, nil
+	}
+	layout, err := t.boundLayout()
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]renderSection, 0, len(layout.Sections))
+	for _, section := range layout.Sections {
+		if section.Group != "" {
+			group := layout.groups[section.Group]
+			sections = append(sections, renderSection{tpl: group, order: group.Order})
+			continue
+		}
+		sections = append(sections, renderSection{tpl: t, order: slotOrder(t.Slots, t.Order, section.Slot)})
+	}
+	return sections, nil
+}
@@ -0,0 +1,209 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+)
+
+func TestValidateSlots(t *testing.T) {
+	groups := map[string]*JSONTemplate{
+		"header": {
+			Name:       "header",
+			Components: map[string]component.Settings{"comp_header": {ID: "comp_header"}},
+			Order:      []string{"comp_header"},
+		},
+	}
+	layouts := make(map[string]*Layout)
+	for name, raw := range map[string]string{
+		"default": `{"name": "default", "sections": [{"group": "header"}, {"slot": "main"}, {"slot": "aside"}]}`,
+		"no_main": `{"name": "no_main", "sections": [{"group": "header"}, {"slot": "aside"}]}`,
+	} {
+		layout, err := ParseLayout([]byte(raw), groups)
+		if err != nil {
+			t.Fatalf("failed to parse layout %s: %v", name, err)
+		}
+		layouts[name] = layout
+	}
+
+	order := []string{"comp_a", "comp_b"}
+	components := map[string]component.Settings{
+		"comp_a": {ID: "comp_a"},
+		"comp_b": {ID: "comp_b"},
+	}
+
+	tests := []struct {
+		name           string
+		layout         string
+		bound          string
+		slots          map[string][]string
+		components     map[string]component.Settings
+		policy         IntegrityPolicy
+		expected       string
+		expectedIssues []string
+		wantErr        string
+	}{
+		{name: "no layout", expected: "map[]"},
+		{name: "slots without layout", slots: map[string][]string{"aside": {"comp_a"}}, wantErr: "without a layout"},
+		{name: "unbound layout", layout: "default", wantErr: "not bound"},
+		{name: "other layout bound", layout: "default", bound: "no_main", wantErr: "got no_main"},
+		{name: "unknown slot", layout: "default", bound: "default", slots: map[string][]string{"footer": {"comp_a"}}, wantErr: "has no slot footer"},
+		{
+			name:       "group collision",
+			layout:     "default",
+			bound:      "default",
+			components: map[string]component.Settings{"comp_header": {ID: "comp_header"}},
+			wantErr:    "already in group header",
+		},
+		{
+			name:   "drop",
+			layout: "default",
+			bound:  "default",
+			slots: map[string][]string{
+				"aside": {"comp_b", "comp_missing"},
+				"main":  {"comp_b"},
+			},
+			expected:       "map[aside:[comp_b] main:[]]",
+			expectedIssues: []string{"slot aside: dangling comp_missing", "slot main: duplicate comp_b"},
+		},
+		{
+			name:   "keep",
+			layout: "default",
+			bound:  "default",
+			slots: map[string][]string{
+				"aside": {"comp_b", "comp_missing"},
+				"main":  {"comp_b"},
+			},
+			policy:         IntegrityKeep,
+			expected:       "map[aside:[comp_b comp_missing] main:[comp_b]]",
+			expectedIssues: []string{"slot aside: dangling comp_missing", "slot main: duplicate comp_b"},
+		},
+		{
+			name:    "unassigned without main slot",
+			layout:  "no_main",
+			bound:   "no_main",
+			slots:   map[string][]string{"aside": {"comp_b"}},
+			wantErr: "component comp_a is not assigned",
+		},
+		{name: "all assigned without main slot", layout: "no_main", bound: "no_main", slots: map[string][]string{"aside": {"comp_a", "comp_b"}}, expected: "map[aside:[comp_a comp_b]]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := &JSONTemplate{Layout: tt.layout, Slots: tt.slots, layout: layouts[tt.bound]}
+			v := &validation{integrity: integrityChecker{policy: tt.policy}}
+			comps := components
+			if tt.components != nil {
+				comps = tt.components
+			}
+
+			slots, err := tpl.validateSlots(v, order, comps)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprint(slots); got != tt.expected {
+				t.Fatalf("expected slots %s, got %s", tt.expected, got)
+			}
+			issues := make([]string, 0, len(v.integrity.issues))
+			for _, issue := range v.integrity.issues {
+				issues = append(issues, issue.String())
+			}
+			if strings.Join(issues, "\n") != strings.Join(tt.expectedIssues, "\n") {
+				t.Fatalf("expected issues %v, got %v", tt.expectedIssues, issues)
+			}
+			// the slots of the template are left untouched
+			if tt.slots != nil && fmt.Sprint(tpl.Slots) != fmt.Sprint(tt.slots) {
+				t.Fatalf("expected template slots to be left untouched, got %v", tpl.Slots)
+			}
+		})
+	}
+}
+
+// countingHandler counts the element values it is handed
+type countingHandler struct {
+	count int
+}
+
+func (h *countingHandler) Handle(ele element.Element, val jsonx.JSONValue, prevErr error) (jsonx.JSONValue, error) {
+	h.count++
+	return val, prevErr
+}
+
+func TestLayoutSectionGroups(t *testing.T) {
+	schemaProvider := pageSchemas(t, nil)
+	groups := map[string]*JSONTemplate{
+		"header": parsePage(t, []byte(`{"name": "header", "order": ["comp_header"], "components": {
+			"comp_header": {"id": "comp_header", "name": "text", "element_settings": {"content": "Shop"}}
+		}}`), schemaProvider),
+		"footer": parsePage(t, []byte(`{"name": "footer", "order": ["comp_footer"], "components": {
+			"comp_footer": {"id": "comp_footer", "name": "text", "element_settings": {"content": "Copyright"}}
+		}}`), schemaProvider),
+	}
+	layout, err := ParseLayout([]byte(`{"name": "default", "sections": [
+		{"group": "header"}, {"slot": "main"}, {"slot": "aside"}, {"group": "footer"}
+	]}`), groups)
+	if err != nil {
+		t.Fatalf("failed to parse layout: %v", err)
+	}
+	if err := layout.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("failed to validate layout: %v", err)
+	}
+
+	// the groups are validated by the layout, not again by every page
+	plainCounter := &countingHandler{}
+	if err := parsePage(t, pageRaw, schemaProvider).Validate(plainCounter); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+	page := mutate(t, pageRaw,
+		mutation{path: "layout", value: "default"},
+		mutation{path: "slots", value: map[string][]string{"aside": {"comp_text", "comp_missing"}}},
+	)
+	tpl := parsePage(t, page, schemaProvider)
+	tpl.SetLayout(layout)
+	layoutCounter := &countingHandler{}
+	if err := tpl.Validate(layoutCounter); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+	if layoutCounter.count != plainCounter.count {
+		t.Fatalf("expected only page elements to be validated (%d), got %d", plainCounter.count, layoutCounter.count)
+	}
+	if slot := tpl.Slots["aside"]; len(slot) != 1 || slot[0] != "comp_text" {
+		t.Fatalf("expected dangling slot entry to be dropped, got %v", slot)
+	}
+
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+	expectedOrder := []string{"comp_header", "comp_title", "comp_text", "comp_footer"}
+	if order := props["order"].([]string); strings.Join(order, ",") != strings.Join(expectedOrder, ",") {
+		t.Fatalf("expected render order %v, got %v", expectedOrder, order)
+	}
+	if _, ok := props["comp_footer"].(map[string]any); !ok {
+		t.Fatal("expected footer group component in props")
+	}
+
+	// page components must not shadow the components of a group
+	collision := parsePage(t, mutate(t, page,
+		mutation{path: "components.comp_header", value: map[string]any{"id": "comp_header", "name": "text"}},
+		mutation{path: "order.-1", value: "comp_header"},
+	), schemaProvider)
+	collision.SetLayout(layout)
+	if err := collision.Validate(); err == nil || !strings.Contains(err.Error(), "component comp_header is already in group header") {
+		t.Fatalf("expected error for component colliding with a group, got %v", err)
+	}
+
+	if _, err := ParseLayout([]byte(`{"name": "broken", "sections": [{"group": "sidebar"}]}`), groups); err == nil {
+		t.Fatal("expected error for unknown group")
+	}
+}
@@ -0,0 +1,49 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+)
+
+// SetPageType binds the schema of the page type, Validate enforces its
+// restrictions on the components of the page. A template declaring a page
+// type fails Validate until its schema is bound.
+func (t *JSONTemplate) SetPageType(schema *pagetype.Schema) {
+	t.pageType = schema
+}
+
+// checkPageType checks the validated slots, order and components against the
+// bound page type, static positions index the page in render order
+func (t *JSONTemplate) checkPageType(
+	slots map[string][]string,
+	order []string,
+	components map[string]component.Settings,
+) error {
+	if t.pageType == nil {
+		if t.PageType != "" {
+			return fmt.Errorf("page type %s is not bound", t.PageType)
+		}
+		return nil
+	}
+	if t.PageType != "" && t.PageType != t.pageType.Name {
+		return fmt.Errorf("template has page type %s, got %s", t.PageType, t.pageType.Name)
+	}
+	return t.pageType.Check(t.pageOrder(slots, order), components)
+}
+
+// pageOrder returns the components of the page in render order, slot by slot
+// when the page has a layout, the groups of the layout are left out
+func (t *JSONTemplate) pageOrder(slots map[string][]string, order []string) []string {
+	if t.Layout == "" || t.layout == nil {
+		return order
+	}
+	rendered := make([]string, 0, len(order))
+	for _, section := range t.layout.Sections {
+		if section.Slot != "" {
+			rendered = append(rendered, slotOrder(slots, order, section.Slot)...)
+		}
+	}
+	return rendered
+}
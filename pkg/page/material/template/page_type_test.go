@@ -0,0 +1,165 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	"github.com/leeseika/cv-demo/pkg/page/material/pagetype"
+)
+
+func TestCheckPageType(t *testing.T) {
+	pageType, err := pagetype.Parse(jsonmodel.PageTypeSchema{
+		Name:             "product",
+		StaticComponents: []jsonmodel.StaticComponent{{ID: "comp_title", Name: "title", Position: 0}},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse page type: %v", err)
+	}
+	layout, err := ParseLayout([]byte(`{"name": "default", "sections": [{"slot": "aside"}, {"slot": "main"}]}`), nil)
+	if err != nil {
+		t.Fatalf("failed to parse layout: %v", err)
+	}
+
+	order := []string{"comp_title", "comp_desc"}
+	components := map[string]component.Settings{
+		"comp_title": {ID: "comp_title", Name: "title"},
+		"comp_desc":  {ID: "comp_desc", Name: "description"},
+	}
+
+	tests := []struct {
+		name     string
+		tpl      *JSONTemplate
+		slots    map[string][]string
+		expected string
+	}{
+		{name: "nothing declared", tpl: &JSONTemplate{}},
+		{name: "not bound", tpl: &JSONTemplate{PageType: "product"}, expected: "page type product is not bound"},
+		{name: "other page type", tpl: &JSONTemplate{PageType: "collection", pageType: pageType}, expected: "template has page type collection, got product"},
+		{name: "order", tpl: &JSONTemplate{pageType: pageType}},
+		{
+			name:  "slots in layout order",
+			tpl:   &JSONTemplate{Layout: "default", layout: layout, pageType: pageType},
+			slots: map[string][]string{"main": {"comp_title"}},
+		},
+		{
+			name:     "slot moves the static component",
+			tpl:      &JSONTemplate{Layout: "default", layout: layout, pageType: pageType},
+			slots:    map[string][]string{"aside": {"comp_desc"}},
+			expected: "static component comp_title must be at position 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tpl.checkPageType(tt.slots, order, components)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidatePageType(t *testing.T) {
+	schemaProvider := pageSchemas(t, map[string][]byte{"banner": []byte(`{"name": "banner", "elements": []}`)})
+	pageType, err := pagetype.Parse(jsonmodel.PageTypeSchema{
+		Name:               "article",
+		AllowedComponents:  []string{"title", "text"},
+		RequiredComponents: []string{"title"},
+		MaxInstances:       map[string]uint8{"title": 1},
+		StaticComponents:   []jsonmodel.StaticComponent{{ID: "comp_title", Name: "title", Position: 0}},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse page type: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		mutations []mutation
+		unbound   bool
+		expected  []string
+	}{
+		{name: "valid"},
+		{name: "valid with page type", mutations: []mutation{{path: "page_type", value: "article"}}},
+		{
+			name:      "page type not bound",
+			mutations: []mutation{{path: "page_type", value: "article"}},
+			unbound:   true,
+			expected:  []string{"page type article is not bound"},
+		},
+		{
+			name: "static position skips disabled components",
+			mutations: []mutation{
+				{path: "components.comp_text.disabled", value: true},
+				{path: "order", value: []string{"comp_text", "comp_title"}},
+			},
+		},
+		{
+			name:      "other page type",
+			mutations: []mutation{{path: "page_type", value: "product"}},
+			expected:  []string{"template has page type product, got article"},
+		},
+		{
+			name: "component not allowed",
+			mutations: []mutation{
+				{path: "components.comp_banner", value: map[string]any{"id": "comp_banner", "name": "banner"}},
+				{path: "order.-1", value: "comp_banner"},
+			},
+			expected: []string{"component comp_banner(banner) is not allowed"},
+		},
+		{
+			name:      "static component moved",
+			mutations: []mutation{{path: "order", value: []string{"comp_text", "comp_title"}}},
+			expected:  []string{"static component comp_title must be at position 0"},
+		},
+		{
+			name:      "static component removed",
+			mutations: []mutation{{path: "order", value: []string{"comp_text"}}},
+			expected:  []string{"required component title is missing", "static component comp_title is missing"},
+		},
+		{
+			name:      "static component disabled",
+			mutations: []mutation{{path: "components.comp_title.disabled", value: true}},
+			expected:  []string{"required component title is missing", "static component comp_title can not be disabled"},
+		},
+		{
+			name: "too many instances",
+			mutations: []mutation{
+				{path: "components.comp_title_2", value: map[string]any{"id": "comp_title_2", "name": "title"}},
+				{path: "order.-1", value: "comp_title_2"},
+			},
+			expected: []string{"component title has 2 instances, max 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := parsePage(t, mutate(t, pageRaw, tt.mutations...), schemaProvider)
+			if !tt.unbound {
+				tpl.SetPageType(pageType)
+			}
+			err := tpl.Validate(NewElementValueChecker())
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %v", tt.expected)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Fatalf("expected error containing %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
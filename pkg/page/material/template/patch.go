@@ -0,0 +1,45 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+// Diff generates the JSON Patch turning t into other,
+// e.g. for change history and review
+func (t *JSONTemplate) Diff(other *JSONTemplate) (jsonx.Patch, error) {
+	from, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json template: %w", err)
+	}
+	to, err := json.Marshal(other)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json template: %w", err)
+	}
+	return jsonx.DiffRaw(from, to), nil
+}
+
+// ApplyPatch applies an incremental change to a copy of t,
+// the patched template still needs to be validated
+func (t *JSONTemplate) ApplyPatch(patch jsonx.Patch) (*JSONTemplate, error) {
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json template: %w", err)
+	}
+	jv := jsonx.JSONValue{RawMessage: raw}
+	if err := patch.Apply(&jv); err != nil {
+		return nil, fmt.Errorf("failed to patch json template: %w", err)
+	}
+	patched, err := ParseJSON(jv.RawMessage, t.schemaProvider)
+	if err != nil {
+		return nil, err
+	}
+	patched.SetThemeSettings(t.themeSchema, t.themeSettings)
+	patched.SetIntegrityPolicy(t.integrityPolicy)
+	patched.settingsMode = t.settingsMode
+	patched.SetLayout(t.layout)
+	patched.SetPageType(t.pageType)
+	return patched, nil
+}
@@ -0,0 +1,66 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+)
+
+func TestApplyPatchAndDiff(t *testing.T) {
+	schemaProvider := pageSchemas(t, nil)
+	tpl := parsePage(t, pageRaw, schemaProvider)
+	if err := tpl.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+
+	// incremental change sent by the editor
+	patch, err := jsonx.DecodePatch([]byte(`[
+		{"op": "replace", "path": "/components/comp_title/element_settings/padding_top", "value": 48},
+		{"op": "move", "from": "/order/1", "path": "/order/0"}
+	]`))
+	if err != nil {
+		t.Fatalf("failed to decode patch: %v", err)
+	}
+	patched, err := tpl.ApplyPatch(patch)
+	if err != nil {
+		t.Fatalf("failed to apply patch: %v", err)
+	}
+	if err := patched.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("failed to validate patched template: %v", err)
+	}
+	if patched.Order[0] != "comp_text" {
+		t.Fatalf("expected comp_text to be moved first, got %v", patched.Order)
+	}
+	if tpl.Order[0] != "comp_title" {
+		t.Fatalf("expected the original template to be untouched, got %v", tpl.Order)
+	}
+
+	// the diff only touches what changed, reordering is an add and a remove
+	diff, err := tpl.Diff(patched)
+	if err != nil {
+		t.Fatalf("failed to diff templates: %v", err)
+	}
+	if len(diff) != 3 {
+		t.Fatalf("expected 3 operations, got %d: %+v", len(diff), diff)
+	}
+
+	// fingerprints only change with the content
+	fingerprint, err := tpl.Fingerprint()
+	if err != nil {
+		t.Fatalf("failed to fingerprint template: %v", err)
+	}
+	patchedFingerprint, err := patched.Fingerprint()
+	if err != nil {
+		t.Fatalf("failed to fingerprint patched template: %v", err)
+	}
+	if fingerprint == patchedFingerprint {
+		t.Fatal("expected patched template to have a different fingerprint")
+	}
+	reparsed := parsePage(t, pageRaw, schemaProvider)
+	if err := reparsed.Validate(NewElementValueChecker()); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+	if reparsedFingerprint, _ := reparsed.Fingerprint(); reparsedFingerprint != fingerprint {
+		t.Fatalf("expected stable fingerprint %s, got %s", fingerprint, reparsedFingerprint)
+	}
+}
@@ -0,0 +1,73 @@
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+	componentschema "github.com/leeseika/cv-demo/pkg/page/tools/component-schema"
+)
+
+// StreamResult holds the top level fields of a streamed json template,
+// components are handed to the callback one by one instead
+type StreamResult struct {
+	Name  string
+	Order []string
+	// Issues are the block integrity issues found with IntegrityDrop, the
+	// components are not checked against the order as they are not held
+	Issues []IntegrityIssue
+	// MigrationChanges are the changes made migrating components to their schema version
+	MigrationChanges []component.MigrationChange
+}
+
+// StreamValidate validates a json template read from r component by component,
+// only a single component is held in memory at a time. Every validated
+// component is passed to fn, a nil fn only validates. Unlike Validate every
+// component in the map is validated, whether it is referenced by the order or not.
+// Undeclared element settings are kept and missing ones are not filled.
+func StreamValidate(
+	r io.Reader,
+	schemaProvider componentschema.ComponentSchemaProvider,
+	fn func(compID string, compSettings component.Settings) error,
+	handlers ...ElementValueHandler,
+) (*StreamResult, error) {
+	if schemaProvider == nil {
+		return nil, fmt.Errorf("schema provider is nil")
+	}
+
+	var res StreamResult
+	v := &validation{integrity: integrityChecker{policy: IntegrityDrop}}
+	stream := jsonx.NewStream(r)
+	err := stream.EachMember(func(key string) error {
+		switch key {
+		case "name":
+			return stream.Decode(&res.Name)
+		case "order":
+			return stream.Decode(&res.Order)
+		case "components":
+			return stream.EachMember(func(compID string) error {
+				var compSettings component.Settings
+				if err := stream.Decode(&compSettings); err != nil {
+					return err
+				}
+				validatedSettings, err := validateComponent(schemaProvider, v, compID, compSettings, handlers)
+				if err != nil {
+					return err
+				}
+				if fn == nil {
+					return nil
+				}
+				return fn(compID, validatedSettings)
+			})
+		default:
+			return stream.Skip()
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream json template: %w", err)
+	}
+	res.Issues = v.integrity.issues
+	res.MigrationChanges = v.migrations
+	return &res, nil
+}
@@ -0,0 +1,40 @@
+package template
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/page/material/component"
+)
+
+func TestStreamValidate(t *testing.T) {
+	schemaProvider := pageSchemas(t, nil)
+
+	var compIDs []string
+	res, err := StreamValidate(
+		bytes.NewReader(pageRaw),
+		schemaProvider,
+		func(compID string, compSettings component.Settings) error {
+			compIDs = append(compIDs, compID)
+			return nil
+		},
+		NewElementValueChecker(),
+	)
+	if err != nil {
+		t.Fatalf("failed to stream template: %v", err)
+	}
+	if res.Name != "page" || len(res.Order) != 2 {
+		t.Fatalf("unexpected stream result %+v", res)
+	}
+	if strings.Join(compIDs, ",") != "comp_title,comp_text" {
+		t.Fatalf("expected components in document order, got %v", compIDs)
+	}
+
+	// an invalid element value is reported with the component it belongs to
+	invalid := mutate(t, pageRaw, mutation{path: "components.comp_title.blocks.blc_title.element_settings.title_size", value: "h9"})
+	_, err = StreamValidate(bytes.NewReader(invalid), schemaProvider, nil, NewElementValueChecker())
+	if err == nil || !strings.Contains(err.Error(), "component comp_title block blc_title") {
+		t.Fatalf("expected error for comp_title, got %v", err)
+	}
+}
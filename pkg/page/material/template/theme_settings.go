@@ -0,0 +1,71 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
+)
+
+// SetThemeSettings binds the global settings of the theme to t, Validate
+// validates them along with the components and ToProps exposes them as settings
+func (t *JSONTemplate) SetThemeSettings(schema *theme.ThemeSettingsSchema, values theme.Settings) {
+	t.themeSchema = schema
+	t.themeSettings = values
+}
+
+// ThemeSettings returns the bound global setting values
+func (t *JSONTemplate) ThemeSettings() theme.Settings {
+	return t.themeSettings
+}
+
+// ValidateThemeSettings runs the global setting values through handlers like
+// the element settings of components, values without element are kept as is
+func ValidateThemeSettings(
+	schema *theme.ThemeSettingsSchema,
+	values theme.Settings,
+	handlers ...ElementValueHandler,
+) (theme.Settings, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("theme settings schema is nil")
+	}
+
+	validated := make(theme.Settings, len(values))
+	for id, val := range values {
+		validated[id] = val
+	}
+	for _, ele := range schema.Elements() {
+		val, ok := validated[ele.GetID()]
+		if !ok {
+			continue
+		}
+		var err error
+		for _, handler := range handlers {
+			val, err = handler.Handle(ele, val, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("theme setting %s value handling failed: %w", ele.GetID(), err)
+		}
+		validated[ele.GetID()] = val
+	}
+	return validated, nil
+}
+
+// themeSettingsToProps converts the bound global setting values to liquid values
+func (t *JSONTemplate) themeSettingsToProps() (map[string]any, error) {
+	props := make(map[string]any)
+	if t.themeSchema == nil {
+		return props, nil
+	}
+	for _, ele := range t.themeSchema.Elements() {
+		val, ok := t.themeSettings[ele.GetID()]
+		if !ok {
+			continue
+		}
+		liquidVal, err := ele.ToLiquid(val)
+		if err != nil {
+			return nil, fmt.Errorf("theme setting %s to liquid failed: %w", ele.GetID(), err)
+		}
+		props[ele.GetID()] = liquidVal
+	}
+	return props, nil
+}
@@ -0,0 +1,65 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/leeseika/cv-demo/pkg/jsonx"
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/theme"
+	"github.com/osteele/liquid/values"
+)
+
+func TestThemeSettings(t *testing.T) {
+	var rawSchema jsonmodel.ThemeSettingsSchema
+	err := json.Unmarshal([]byte(`{"categories": [{"id": "typography", "name": "Typography", "elements": [
+		{"type": "select", "id": "heading_font", "default": "sans_serif", "label": "Font", "options": [
+			{"value": "serif", "label": "Serif"}, {"value": "sans_serif", "label": "Sans serif"}
+		]},
+		{"type": "range", "id": "base_font_size", "min": 12, "max": 24, "default": 16, "label": "Size"}
+	]}]}`), &rawSchema)
+	if err != nil {
+		t.Fatalf("failed to unmarshal theme settings schema: %v", err)
+	}
+	schema, err := theme.Parse(rawSchema)
+	if err != nil {
+		t.Fatalf("failed to parse theme settings schema: %v", err)
+	}
+
+	tpl := parsePage(t, pageRaw, pageSchemas(t, nil))
+	headingFont, _ := jsonx.NewString("serif")
+	tpl.SetThemeSettings(schema, theme.Settings{
+		"heading_font":   *headingFont,
+		"base_font_size": *jsonx.NewNumber(int64(100)),
+		"retired":        *jsonx.NewNumber(int64(1)),
+	})
+	if err := tpl.Validate(NewElementValueChecker(), NewElementValueDefaultSetter("en-US", nil)); err != nil {
+		t.Fatalf("failed to validate template: %v", err)
+	}
+	settings := tpl.ThemeSettings()
+	if fontSize := settings["base_font_size"]; fontSize.Int() != 16 {
+		t.Fatalf("expected out of range font size to fall back to 16, got %s", fontSize.RawMessage)
+	}
+	if _, ok := settings["retired"]; !ok {
+		t.Fatal("expected values without element to be kept")
+	}
+
+	props, err := tpl.ToProps("en-US")
+	if err != nil {
+		t.Fatalf("failed to convert template to props: %v", err)
+	}
+	settingsProps := props["settings"].(map[string]any)
+	if font := settingsProps["heading_font"].(values.Value).Interface(); font != "serif" {
+		t.Fatalf("expected heading font serif, got %v", font)
+	}
+	if _, ok := settingsProps["retired"]; ok {
+		t.Fatal("expected values without element not to be rendered")
+	}
+	if _, ok := props["comp_title"]; !ok {
+		t.Fatal("expected components next to the theme settings")
+	}
+
+	if _, err := ValidateThemeSettings(nil, settings); err == nil {
+		t.Fatal("expected error for a nil schema")
+	}
+}
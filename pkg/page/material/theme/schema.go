@@ -0,0 +1,99 @@
+package theme
+
+import (
+	"fmt"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element"
+	"github.com/leeseika/cv-demo/pkg/page/material/component/element/field"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+// ThemeSettingsSchema declares the global settings of a theme, such as brand
+// colors, typography and social links, every component can read them.
+// Categories only group the elements for the editor, element ids are unique
+// across all categories.
+type ThemeSettingsSchema struct {
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	ID       string                  `json:"id"`
+	Name     field.TranslatableField `json:"name"`
+	Elements []element.Element       `json:"elements"`
+}
+
+// Parse parses the raw theme settings schema without localizing it,
+// use Localize to get a copy for a specific locale.
+func Parse(
+	raw jsonmodel.ThemeSettingsSchema,
+) (*ThemeSettingsSchema, error) {
+	categories := make([]Category, 0, len(raw.Categories))
+	categoryIDs := make(map[string]struct{}, len(raw.Categories))
+	elementIDs := make(map[string]string)
+	for _, rawCategory := range raw.Categories {
+		if _, ok := categoryIDs[rawCategory.ID]; ok {
+			return nil, fmt.Errorf("duplicated category %s", rawCategory.ID)
+		}
+		categoryIDs[rawCategory.ID] = struct{}{}
+
+		elements, err := element.UnmarshalElements(rawCategory.Elements)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal element of category %s: %w", rawCategory.ID, err)
+		}
+		for _, ele := range elements {
+			if err := ele.Validate(); err != nil {
+				return nil, fmt.Errorf("element %s(%s) validation failed: %w", ele.GetID(), ele.EleType(), err)
+			}
+			if categoryID, ok := elementIDs[ele.GetID()]; ok {
+				return nil, fmt.Errorf("element %s of category %s is already declared in category %s", ele.GetID(), rawCategory.ID, categoryID)
+			}
+			elementIDs[ele.GetID()] = rawCategory.ID
+		}
+		categories = append(categories, Category{
+			ID:       rawCategory.ID,
+			Name:     rawCategory.Name,
+			Elements: elements,
+		})
+	}
+	schema := &ThemeSettingsSchema{
+		Categories: categories,
+	}
+	// visible_if may reference settings of other categories
+	if _, err := element.NewVisibility(schema.Elements()); err != nil {
+		return nil, fmt.Errorf("invalid visible_if: %w", err)
+	}
+	return schema, nil
+}
+
+// Localize returns a copy of the schema with all translatable fields
+// resolved for the given locale, the receiver is left untouched.
+func (s *ThemeSettingsSchema) Localize(
+	locale string,
+	localeProvider locale.LocaleProvider,
+) *ThemeSettingsSchema {
+	categories := make([]Category, 0, len(s.Categories))
+	for _, category := range s.Categories {
+		elements := make([]element.Element, 0, len(category.Elements))
+		for _, ele := range category.Elements {
+			elements = append(elements, ele.Localize(locale, localeProvider))
+		}
+		categories = append(categories, Category{
+			ID:       category.ID,
+			Name:     category.Name.Localize(locale, localeProvider),
+			Elements: elements,
+		})
+	}
+	return &ThemeSettingsSchema{
+		Categories: categories,
+	}
+}
+
+// Elements returns the elements of all categories in declaration order
+func (s *ThemeSettingsSchema) Elements() []element.Element {
+	var elements []element.Element
+	for _, category := range s.Categories {
+		elements = append(elements, category.Elements...)
+	}
+	return elements
+}
@@ -0,0 +1,88 @@
+package theme
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonmodel "github.com/leeseika/cv-demo/pkg/model/json"
+	"github.com/leeseika/cv-demo/pkg/page/tools/locale"
+)
+
+func parseSchema(raw string) (*ThemeSettingsSchema, error) {
+	var rawSchema jsonmodel.ThemeSettingsSchema
+	if err := json.Unmarshal([]byte(raw), &rawSchema); err != nil {
+		return nil, err
+	}
+	return Parse(rawSchema)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{
+			name: "valid",
+			raw: `{"categories": [
+				{"id": "colors", "name": "Colors", "elements": [{"type": "text", "id": "brand_color", "default": "#121212", "label": "Brand"}]},
+				{"id": "typography", "name": "Typography", "elements": [
+					{"type": "range", "id": "base_font_size", "min": 12, "max": 24, "default": 16, "label": "Size", "visible_if": "brand_color != ''"}
+				]}
+			]}`,
+		},
+		{
+			name: "duplicated category",
+			raw: `{"categories": [
+				{"id": "a", "name": "A", "elements": []},
+				{"id": "a", "name": "A", "elements": []}
+			]}`,
+			expected: "duplicated category a",
+		},
+		{
+			name: "element declared in two categories",
+			raw: `{"categories": [
+				{"id": "a", "name": "A", "elements": [{"type": "text", "id": "color", "default": "", "label": "Color"}]},
+				{"id": "b", "name": "B", "elements": [{"type": "text", "id": "color", "default": "", "label": "Color"}]}
+			]}`,
+			expected: "element color of category b is already declared in category a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := parseSchema(tt.raw)
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(schema.Elements()) != 2 {
+					t.Fatalf("expected the elements of every category, got %d", len(schema.Elements()))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Fatalf("expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	schema, err := parseSchema(`{"categories": [
+		{"id": "typography", "name": "t:theme.typography", "elements": [{"type": "text", "id": "font", "default": "", "label": "t:theme.font"}]}
+	]}`)
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	provider := locale.NewJSONProvider([]byte(`{"theme": {"typography": "排版", "font": "字体"}}`))
+
+	localized := schema.Localize("zh-CN", provider)
+	if name := localized.Categories[0].Name.String(); name != "排版" {
+		t.Fatalf("expected localized category name 排版, got %s", name)
+	}
+	if name := schema.Categories[0].Name.String(); name != "t:theme.typography" {
+		t.Fatalf("expected the schema to keep its translation key, got %s", name)
+	}
+}
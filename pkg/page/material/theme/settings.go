@@ -0,0 +1,6 @@
+package theme
+
+import "github.com/leeseika/cv-demo/pkg/jsonx"
+
+// Settings holds the global setting values of a theme by element id
+type Settings map[string]jsonx.JSONValue
@@ -0,0 +1,206 @@
+package locale
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+type Direction string
+
+const (
+	DirectionLTR Direction = "ltr"
+	DirectionRTL Direction = "rtl"
+)
+
+// rtlLanguages lists the languages written from right to left
+var rtlLanguages = map[string]struct{}{
+	"ar": {},
+	"he": {},
+	"fa": {},
+	"ur": {},
+}
+
+type numberFormat struct {
+	decimalSep string
+	groupSep   string
+	// unitSep separates a value from its unit, e.g. "36px" versus "36 px"
+	unitSep string
+	// currencySuffix puts the currency symbol after the amount
+	currencySuffix bool
+	dateLayout     string
+}
+
+var defaultNumberFormat = numberFormat{
+	decimalSep: ".",
+	groupSep:   ",",
+	unitSep:    "",
+	dateLayout: "Jan 2, 2006",
+}
+
+// numberFormats is keyed by full locale code first and language second
+var numberFormats = map[string]numberFormat{
+	"en":    defaultNumberFormat,
+	"en-GB": {decimalSep: ".", groupSep: ",", unitSep: "", dateLayout: "2 Jan 2006"},
+	"zh":    {decimalSep: ".", groupSep: ",", unitSep: "", dateLayout: "2006年1月2日"},
+	"ja":    {decimalSep: ".", groupSep: ",", unitSep: "", dateLayout: "2006/01/02"},
+	"de":    {decimalSep: ",", groupSep: ".", unitSep: " ", currencySuffix: true, dateLayout: "02.01.2006"},
+	"fr":    {decimalSep: ",", groupSep: " ", unitSep: " ", currencySuffix: true, dateLayout: "02/01/2006"},
+	"es":    {decimalSep: ",", groupSep: ".", unitSep: " ", currencySuffix: true, dateLayout: "02/01/2006"},
+	"ru":    {decimalSep: ",", groupSep: " ", unitSep: " ", currencySuffix: true, dateLayout: "02.01.2006"},
+	"ar":    {decimalSep: ".", groupSep: ",", unitSep: " ", currencySuffix: true, dateLayout: "02/01/2006"},
+	"he":    {decimalSep: ".", groupSep: ",", unitSep: " ", currencySuffix: true, dateLayout: "02.01.2006"},
+}
+
+type currencyFormat struct {
+	symbol   string
+	decimals int
+}
+
+var currencyFormats = map[string]currencyFormat{
+	"USD": {symbol: "$", decimals: 2},
+	"EUR": {symbol: "€", decimals: 2},
+	"GBP": {symbol: "£", decimals: 2},
+	"CNY": {symbol: "¥", decimals: 2},
+	"JPY": {symbol: "¥", decimals: 0},
+	"ILS": {symbol: "₪", decimals: 2},
+	"SAR": {symbol: "ر.س", decimals: 2},
+}
+
+// Formatter formats numbers, currencies, dates and units for a locale
+type Formatter struct {
+	code      string
+	language  string
+	direction Direction
+	format    numberFormat
+}
+
+func NewFormatter(code string) *Formatter {
+	code = strings.ReplaceAll(strings.TrimSpace(code), "_", "-")
+	// canonical casing, e.g. "en-gb" is looked up as "en-GB"
+	if tag, err := language.Parse(code); err == nil {
+		code = tag.String()
+	}
+	lang := strings.ToLower(strings.SplitN(code, "-", 2)[0])
+
+	format, ok := numberFormats[code]
+	if !ok {
+		format, ok = numberFormats[lang]
+	}
+	if !ok {
+		format = defaultNumberFormat
+	}
+
+	direction := DirectionLTR
+	if _, ok := rtlLanguages[lang]; ok {
+		direction = DirectionRTL
+	}
+
+	return &Formatter{
+		code:      code,
+		language:  lang,
+		direction: direction,
+		format:    format,
+	}
+}
+
+func (f *Formatter) Code() string {
+	return f.code
+}
+
+func (f *Formatter) Direction() Direction {
+	return f.direction
+}
+
+func (f *Formatter) IsRTL() bool {
+	return f.direction == DirectionRTL
+}
+
+// FormatNumber formats n with grouped digits, a negative precision keeps
+// as many decimals as needed
+func (f *Formatter) FormatNumber(n float64, precision int) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	if precision >= 0 {
+		n = roundHalfAwayFromZero(n, precision)
+	}
+	s := strconv.FormatFloat(math.Abs(n), 'f', precision, 64)
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if n < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(f.format.groupSep)
+		}
+		b.WriteRune(digit)
+	}
+	if hasFrac {
+		b.WriteString(f.format.decimalSep)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// roundHalfAwayFromZero rounds as shoppers expect, FormatFloat rounds half to
+// even. Values too large to carry decimals at precision are returned as they
+// are, scaling them would lose precision or overflow to Inf.
+func roundHalfAwayFromZero(n float64, precision int) float64 {
+	pow := math.Pow10(precision)
+	scaled := n * pow
+	if math.IsInf(scaled, 0) || math.IsNaN(scaled) || math.Abs(scaled) >= 1<<53 {
+		return n
+	}
+	return math.Round(scaled) / pow
+}
+
+// FormatCurrency formats amount with the symbol of the ISO 4217 currency code,
+// unknown currencies fall back to the code itself
+func (f *Formatter) FormatCurrency(amount float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	cf, ok := currencyFormats[currency]
+	if !ok {
+		cf = currencyFormat{symbol: currency, decimals: 2}
+	}
+	num := f.FormatNumber(amount, cf.decimals)
+	if f.format.currencySuffix {
+		return num + " " + cf.symbol
+	}
+	if strings.HasPrefix(num, "-") {
+		return "-" + cf.symbol + strings.TrimPrefix(num, "-")
+	}
+	return cf.symbol + num
+}
+
+func (f *Formatter) FormatDate(t time.Time) string {
+	return t.Format(f.format.dateLayout)
+}
+
+// FormatUnit joins a formatted number with its unit, e.g. "36px" or "36 px"
+func (f *Formatter) FormatUnit(n float64, unit string) string {
+	num := f.FormatNumber(n, -1)
+	if unit == "" {
+		return num
+	}
+	// percent signs are never separated in the locales we support
+	if unit == "%" {
+		return num + unit
+	}
+	return num + f.format.unitSep + unit
+}
+
+// ToLiquid exposes the locale as the `locale` object in Liquid
+func (f *Formatter) ToLiquid() map[string]any {
+	return map[string]any{
+		"code":      f.code,
+		"language":  f.language,
+		"direction": string(f.direction),
+		"rtl":       f.IsRTL(),
+	}
+}
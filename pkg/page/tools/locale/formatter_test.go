@@ -0,0 +1,150 @@
+package locale
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/osteele/liquid"
+)
+
+func TestFormatterDirection(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected Direction
+	}{
+		{code: "en-US", expected: DirectionLTR},
+		{code: "zh-CN", expected: DirectionLTR},
+		{code: "ar", expected: DirectionRTL},
+		{code: "he-IL", expected: DirectionRTL},
+		{code: "", expected: DirectionLTR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			f := NewFormatter(tt.code)
+			if f.Direction() != tt.expected {
+				t.Fatalf("expected direction %s, got %s", tt.expected, f.Direction())
+			}
+			if f.ToLiquid()["rtl"] != (tt.expected == DirectionRTL) {
+				t.Fatalf("expected liquid rtl to match direction %s", tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatterFormat(t *testing.T) {
+	date := time.Date(2025, time.March, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		code         string
+		currencyCode string
+		number       string
+		currency     string
+		date         string
+		unit         string
+	}{
+		{code: "en-US", currencyCode: "USD", number: "-1,234,567.5", currency: "$1,234.50", date: "Mar 7, 2025", unit: "36px"},
+		{code: "de-DE", currencyCode: "eur", number: "-1.234.567,5", currency: "1.234,50 €", date: "07.03.2025", unit: "36 px"},
+		{code: "fr-FR", currencyCode: "EUR", number: "-1 234 567,5", currency: "1 234,50 €", date: "07/03/2025", unit: "36 px"},
+		{code: "zh-CN", currencyCode: "JPY", number: "-1,234,567.5", currency: "¥1,235", date: "2025年3月7日", unit: "36px"},
+		{code: "en-gb", currencyCode: "GBP", number: "-1,234,567.5", currency: "£1,234.50", date: "7 Mar 2025", unit: "36px"},
+		{code: "EN_GB", currencyCode: "GBP", number: "-1,234,567.5", currency: "£1,234.50", date: "7 Mar 2025", unit: "36px"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			f := NewFormatter(tt.code)
+			if got := f.FormatNumber(-1234567.5, -1); got != tt.number {
+				t.Errorf("expected number %q, got %q", tt.number, got)
+			}
+			if got := f.FormatCurrency(1234.5, tt.currencyCode); got != tt.currency {
+				t.Errorf("expected currency %q, got %q", tt.currency, got)
+			}
+			if got := f.FormatDate(date); got != tt.date {
+				t.Errorf("expected date %q, got %q", tt.date, got)
+			}
+			if got := f.FormatUnit(36, "px"); got != tt.unit {
+				t.Errorf("expected unit %q, got %q", tt.unit, got)
+			}
+		})
+	}
+}
+
+func TestFormatNumberLarge(t *testing.T) {
+	f := NewFormatter("en-US")
+
+	tests := []struct {
+		name      string
+		n         float64
+		precision int
+		expected  string
+	}{
+		{name: "half away from zero", n: -2.5, precision: 0, expected: "-3"},
+		{name: "max float", n: math.MaxFloat64, precision: 2, expected: "179,769,313"},
+		{name: "beyond float precision", n: 1e300, precision: 20, expected: "1,000,000,000"},
+		{name: "huge precision", n: 1.5, precision: 400, expected: "1.500000"},
+		{name: "zero with huge precision", n: 0, precision: 400, expected: "0.000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.FormatNumber(tt.n, tt.precision)
+			if !strings.HasPrefix(got, tt.expected) {
+				t.Fatalf("expected number starting with %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLiquidFiltersRender(t *testing.T) {
+	engine := liquid.NewEngine()
+	for name, fn := range LiquidFilters() {
+		engine.RegisterFilter(name, fn)
+	}
+
+	bindings := map[string]any{
+		"price":        1234.5,
+		"sold":         1234567,
+		"published_at": time.Date(2025, time.March, 7, 0, 0, 0, 0, time.UTC),
+		"locale":       NewFormatter("de-DE").ToLiquid(),
+	}
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{source: `{{ price | format_currency: "EUR", locale.code }}`, expected: "1.234,50 €"},
+		{source: `{{ sold | format_number: locale.code }}`, expected: "1.234.567"},
+		{source: `{{ price | format_number: "en-US", 2 }}`, expected: "1,234.50"},
+		{source: `{{ published_at | format_date: locale.code }}`, expected: "07.03.2025"},
+		{source: `{{ 36 | format_unit: "px", locale.code }}`, expected: "36 px"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := engine.ParseAndRenderString(tt.source, bindings)
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLiquidFilters(t *testing.T) {
+	filters := LiquidFilters()
+
+	formatNumber, ok := filters["format_number"].(func(float64, string, ...int) string)
+	if !ok {
+		t.Fatal("expected format_number filter")
+	}
+	if got := formatNumber(1234.567, "en-US", 2); got != "1,234.57" {
+		t.Fatalf("expected 1,234.57, got %q", got)
+	}
+	if got := formatNumber(1234.5, "de-DE"); got != "1.234,5" {
+		t.Fatalf("expected 1.234,5, got %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package locale
+
+import "time"
+
+// LiquidFilters returns the locale aware filters keyed by filter name,
+// register them with liquid.Engine.RegisterFilter. Every filter takes the
+// locale code as an argument, e.g.
+//
+//	{{ product.price | format_currency: "USD", locale.code }}
+//	{{ product.sold | format_number: locale.code }}
+//	{{ product.published_at | format_date: locale.code }}
+func LiquidFilters() map[string]any {
+	return map[string]any{
+		"format_number": func(n float64, code string, precision ...int) string {
+			p := -1
+			if len(precision) > 0 {
+				p = precision[0]
+			}
+			return NewFormatter(code).FormatNumber(n, p)
+		},
+		"format_currency": func(amount float64, currency string, code string) string {
+			return NewFormatter(code).FormatCurrency(amount, currency)
+		},
+		"format_date": func(t time.Time, code string) string {
+			return NewFormatter(code).FormatDate(t)
+		},
+		"format_unit": func(n float64, unit string, code string) string {
+			return NewFormatter(code).FormatUnit(n, unit)
+		},
+	}
+}